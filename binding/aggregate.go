@@ -0,0 +1,104 @@
+package binding
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// 该文件实现聚合函数 sum/count/avg/min/max：在 Aggregate 创建的作用域中，参数不直接求值，
+// 而是针对每一行的作用域分别求值后汇总，例如表格合计行中的 `${sum(item.price * item.qty)}`。
+
+// aggregates 按名称汇总各行的取值，vals 中已去掉 nil。
+var aggregates = map[string]func(vals []any) (any, error){
+	"sum":   sumValues,
+	"count": func(vals []any) (any, error) { return float64(len(vals)), nil },
+	"avg":   avgValues,
+	"min":   func(vals []any) (any, error) { return extremum(vals, math.Min) },
+	"max":   func(vals []any) (any, error) { return extremum(vals, math.Max) },
+}
+
+// Aggregate 创建可使用聚合函数的子作用域：sum/count/avg/min/max 的参数在 rows 的每个作用域中分别求值，
+// 值为 nil 的行被跳过；count() 不带参数时返回行数。rows 为空时 sum 与 count 为 0，avg/min/max 为 nil。
+// 在该作用域中，这些名称优先于同名的注册函数。
+func (s *Scope) Aggregate(rows []*Scope) *Scope {
+	child := s.Child()
+	child.rows = rows
+	child.aggregate = true
+	return child
+}
+
+// isAggregate 判断调用是否应按聚合函数处理。
+func (ev *evaluator) isAggregate(n *dsl.CallExpr) bool {
+	_, ok := aggregates[n.Func]
+	return ok && ev.scope.aggregate
+}
+
+// callAggregate 在每一行的作用域中对参数求值并汇总；行中不存在的路径同样记录为缺失。
+func (ev *evaluator) callAggregate(n *dsl.CallExpr) (any, error) {
+	if n.Func == "count" && len(n.Args) == 0 {
+		return float64(len(ev.scope.rows)), nil
+	}
+	if len(n.Args) != 1 {
+		return nil, fmt.Errorf("%s: 聚合函数 %s 需要 1 个参数，实际为 %d", n.Pos, n.Func, len(n.Args))
+	}
+	vals := make([]any, 0, len(ev.scope.rows))
+	for _, row := range ev.scope.rows {
+		sub := evaluator{scope: row}
+		val, err := sub.eval(n.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		ev.missing = append(ev.missing, sub.missing...)
+		if val != nil {
+			vals = append(vals, val)
+		}
+	}
+	out, err := aggregates[n.Func](vals)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", n.Pos, n.Func, err)
+	}
+	return out, nil
+}
+
+func sumValues(vals []any) (any, error) {
+	total := 0.0
+	for _, v := range vals {
+		f, err := numberArg(v)
+		if err != nil {
+			return nil, err
+		}
+		total += f
+	}
+	return total, nil
+}
+
+func avgValues(vals []any) (any, error) {
+	if len(vals) == 0 {
+		return nil, nil
+	}
+	total, err := sumValues(vals)
+	if err != nil {
+		return nil, err
+	}
+	return total.(float64) / float64(len(vals)), nil
+}
+
+func extremum(vals []any, pick func(a, b float64) float64) (any, error) {
+	if len(vals) == 0 {
+		return nil, nil
+	}
+	out, err := numberArg(vals[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vals[1:] {
+		f, err := numberArg(v)
+		if err != nil {
+			return nil, err
+		}
+		out = pick(out, f)
+	}
+	return out, nil
+}
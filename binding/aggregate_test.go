@@ -0,0 +1,63 @@
+package binding
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ByLCY/papyrus/dsl"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// TestAggregate 验证聚合函数针对每一行求值、跳过 nil，以及在普通作用域中不生效。
+func TestAggregate(t *testing.T) {
+	items := []any{
+		map[string]any{"price": 8.0, "qty": 2.0},
+		map[string]any{"price": 5.0, "qty": 2.0},
+		map[string]any{"price": 2.0},
+	}
+	root := NewScope(map[string]any{"items": items, "currency": "¥"})
+	rows := make([]*Scope, len(items))
+	for i, item := range items {
+		rows[i] = root.Child()
+		rows[i].Set("item", item)
+	}
+	scope := root.Aggregate(rows)
+	cases := map[string]any{
+		`sum(item.price * default(item.qty, 1))`:      28.0,
+		`count()`:                                     3.0,
+		`count(item.qty)`:                             2.0,
+		`avg(item.price)`:                             5.0,
+		`min(item.price)`:                             2.0,
+		`max(item.price) + 1`:                         9.0,
+		`formatMoney(sum(item.price), data.currency)`: "¥15.00",
+	}
+	for src, want := range cases {
+		node, err := dsl.ParseExpr(src, lexer.Position{Line: 1, Column: 1})
+		if err != nil {
+			t.Fatalf("解析 %q 失败: %v", src, err)
+		}
+		got, err := Eval(node, scope.Child())
+		if err != nil {
+			t.Fatalf("求值 %q 失败: %v", src, err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("求值 %q 期望 %v，实际 %v", src, want, got)
+		}
+	}
+
+	if got, err := Expand(`${avg(item.price)}|${sum(item.qty)}`, lexer.Position{}, root.Aggregate(nil)); err != nil || got != "|0" {
+		t.Fatalf("没有行时 avg 应为空、sum 应为 0，实际 %q (%v)", got, err)
+	}
+	if _, err := Expand(`${sum(item.price)}`, lexer.Position{}, root); err == nil {
+		t.Fatalf("普通作用域中 sum 未定义，应报错")
+	}
+	if _, err := Expand(`${sum(item.name)}`, lexer.Position{}, NewScope(nil).Aggregate([]*Scope{rowWith("item", map[string]any{"name": "A"})})); err == nil {
+		t.Fatalf("对非数字求和应报错")
+	}
+}
+
+func rowWith(name string, val any) *Scope {
+	s := NewScope(nil)
+	s.Set(name, val)
+	return s
+}
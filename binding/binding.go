@@ -1,82 +1,184 @@
 package binding
 
 import (
-	"fmt"
-	"regexp"
-	"strconv"
-	"strings"
+	"github.com/ByLCY/papyrus/dsl"
+	"github.com/alecthomas/participle/v2/lexer"
 )
 
-var exprPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+// RootName 是引用根数据的保留名称：`data.items` 与 `items` 等价（根数据自身含 data 字段时优先取该字段）。
+const RootName = "data"
 
-// Interpolate 将文本中的 ${path.to.value} 替换为 data 中的值。
-// 若 data 为空或路径不存在，则返回原占位符。
+// Scope 是数据绑定的变量作用域。查找变量时先由内向外检查 let/for 定义的变量，再回落到根数据。
+type Scope struct {
+	parent  *Scope
+	root    any
+	vars    map[string]any
+	funcs   FuncMap
+	missing *missingReport
+	// rows 为聚合函数遍历的各行作用域，aggregate 表示作用域由 Aggregate 创建（见 aggregate.go）
+	rows      []*Scope
+	aggregate bool
+}
+
+// NewScope 以 root 为根数据创建顶层作用域。
+func NewScope(root any) *Scope {
+	return &Scope{root: root}
+}
+
+// ScopeOf 将任意数据包装为作用域；data 本身为 *Scope 时直接返回。
+func ScopeOf(data any) *Scope {
+	if s, ok := data.(*Scope); ok && s != nil {
+		return s
+	}
+	return NewScope(data)
+}
+
+// Child 创建继承当前作用域的子作用域，子作用域中定义的变量不会影响外层。
+func (s *Scope) Child() *Scope {
+	return &Scope{parent: s, root: s.root, funcs: s.funcs, missing: s.missing, rows: s.rows, aggregate: s.aggregate}
+}
+
+// Funcs 注册表达式中可调用的函数，同名函数会被覆盖；返回 s 以便链式调用。
+// 应在创建子作用域之前注册，子作用域共享同一份函数表。
+func (s *Scope) Funcs(funcs FuncMap) *Scope {
+	if len(funcs) == 0 {
+		return s
+	}
+	merged := make(FuncMap, len(s.funcs)+len(funcs))
+	for name, fn := range s.funcs {
+		merged[name] = fn
+	}
+	for name, fn := range funcs {
+		merged[name] = fn
+	}
+	s.funcs = merged
+	return s
+}
+
+// Func 查找已注册的函数，未注册时回落到内置函数（见 Builtins）。
+func (s *Scope) Func(name string) (any, bool) {
+	if fn, ok := s.funcs[name]; ok {
+		return fn, true
+	}
+	fn, ok := builtins[name]
+	return fn, ok
+}
+
+// Set 在当前作用域中定义变量。
+func (s *Scope) Set(name string, val any) {
+	if s.vars == nil {
+		s.vars = map[string]any{}
+	}
+	s.vars[name] = val
+}
+
+// Root 返回根数据。
+func (s *Scope) Root() any {
+	return s.root
+}
+
+// Lookup 按作用域链查找变量，未命中时依次尝试根数据字段与保留名称 data。
+func (s *Scope) Lookup(name string) (any, bool) {
+	for cur := s; cur != nil; cur = cur.parent {
+		if val, ok := cur.vars[name]; ok {
+			return val, true
+		}
+	}
+	if val, ok := descendMap(s.root, name); ok {
+		return val, true
+	}
+	if name == RootName && s.root != nil {
+		return s.root, true
+	}
+	return nil, false
+}
+
+// Interpolate 将文本中的 ${expr} 替换为表达式的值。
+// data 可以是根数据或 *Scope；表达式求值失败时保留原占位符，路径不存在时按作用域的 Missing 策略处理（默认保留）。
 func Interpolate(text string, data any) string {
 	if data == nil {
 		return text
 	}
-	return exprPattern.ReplaceAllStringFunc(text, func(match string) string {
-		groups := exprPattern.FindStringSubmatch(match)
-		if len(groups) < 2 {
-			return match
-		}
-		path := strings.TrimSpace(groups[1])
-		if path == "" {
-			return match
-		}
-		if val, ok := resolvePath(data, path); ok {
-			return fmt.Sprint(val)
-		}
-		return match
-	})
-}
-
-func resolvePath(data any, path string) (any, bool) {
-	current := data
-	segments := strings.Split(path, ".")
-	for _, segment := range segments {
-		name, indexes := parseSegment(segment)
-		if name != "" {
-			var ok bool
-			current, ok = descendMap(current, name)
-			if !ok {
-				return nil, false
-			}
+	tpl := compileTemplate(text, lexer.Position{})
+	out, _ := tpl.execute(ScopeOf(data), false, nil)
+	return out
+}
+
+// Expand 与 Interpolate 相同，但会返回表达式的语法与求值错误。
+// pos 为文本在 DSL 源码中的起始位置，用于计算错误与未解析绑定的行列号。
+func Expand(text string, pos lexer.Position, data any) (string, error) {
+	return ExpandMarkup(text, pos, data, nil)
+}
+
+// ExpandMarkup 与 Expand 相同，但每个插值结果在写入前先经过 escape 转义，
+// 使数据中的字符不会被当作模板中的行内标记解析；Markup 类型的值（如 raw() 的结果）原样写入。
+func ExpandMarkup(text string, pos lexer.Position, data any, escape func(string) string) (string, error) {
+	tpl := compileTemplate(text, pos)
+	return tpl.execute(ScopeOf(data), true, escape)
+}
+
+// ExpandExpr 对单个表达式求值并按插值规则输出文本，等价于展开只含 `${node}` 的字符串：
+// 路径不存在时按作用域的 Missing 策略处理，escape 的用法与 ExpandMarkup 相同。
+func ExpandExpr(node dsl.Expr, data any, escape func(string) string) (string, error) {
+	tpl := &template{parts: []templatePart{{raw: "${" + node.String() + "}", expr: node}}}
+	return tpl.execute(ScopeOf(data), true, escape)
+}
+
+// Markup 表示可信的标记片段，插值时不做转义。
+type Markup string
+
+// Resolve 在 data（根数据或 *Scope）中查找 path，例如 items[0].name。
+func Resolve(data any, path string) (any, bool) {
+	node, err := dsl.ParseExpr(path, lexer.Position{})
+	if err != nil {
+		return nil, false
+	}
+	ev := evaluator{scope: ScopeOf(data)}
+	val, err := ev.eval(node)
+	if err != nil || len(ev.missing) > 0 {
+		return nil, false
+	}
+	return val, true
+}
+
+// Truthy 判断值在条件语句中是否为真：nil（含 nil 指针）、false、0、空字符串与空集合视为假。
+func Truthy(val any) bool {
+	switch v := normalize(val).(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case float64:
+		return v != 0
+	case int:
+		return v != 0
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	default:
+		if f, ok := toFloat(v); ok {
+			return f != 0
 		}
-		for _, idxStr := range indexes {
-			idx, err := strconv.Atoi(idxStr)
-			if err != nil {
-				return nil, false
-			}
-			var ok bool
-			current, ok = descendArray(current, idx)
-			if !ok {
-				return nil, false
-			}
+		if n, ok := reflectLen(v); ok {
+			return n > 0
 		}
+		return true
 	}
-	return current, true
-}
-
-func parseSegment(segment string) (string, []string) {
-	name := segment
-	indexes := []string{}
-	if i := strings.Index(segment, "["); i != -1 {
-		name = segment[:i]
-		rest := segment[i:]
-		for len(rest) > 0 {
-			if rest[0] != '[' {
-				break
-			}
-			end := strings.IndexByte(rest, ']')
-			if end == -1 {
-				break
-			}
-			indexes = append(indexes, rest[1:end])
-			rest = rest[end+1:]
-		}
+}
+
+// Items 将可遍历的值（任意切片或数组）转换为元素列表；nil 视为空列表。
+func Items(val any) ([]any, bool) {
+	switch v := val.(type) {
+	case nil:
+		return nil, true
+	case []interface{}:
+		return v, true
+	default:
+		return reflectItems(v)
 	}
-	return name, indexes
 }
 
 func descendMap(current any, key string) (any, bool) {
@@ -84,8 +186,10 @@ func descendMap(current any, key string) (any, bool) {
 	case map[string]interface{}:
 		val, ok := c[key]
 		return val, ok
-	default:
+	case nil:
 		return nil, false
+	default:
+		return reflectField(c, key)
 	}
 }
 
@@ -96,7 +200,9 @@ func descendArray(current any, idx int) (any, bool) {
 			return nil, false
 		}
 		return c[idx], true
-	default:
+	case nil:
 		return nil, false
+	default:
+		return reflectIndex(c, idx)
 	}
 }
@@ -0,0 +1,198 @@
+package binding
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ByLCY/papyrus/dsl"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+func testData() map[string]any {
+	return map[string]any{
+		"user":  map[string]any{"name": "Papyrus", "vip": true},
+		"price": 12.5,
+		"qty":   4.0,
+		"items": []any{
+			map[string]any{"name": "A"},
+			map[string]any{"name": "B"},
+		},
+		"empty": "",
+	}
+}
+
+// TestEval 验证运算符、优先级、短路、三元表达式与函数调用的求值结果。
+func TestEval(t *testing.T) {
+	scope := NewScope(testData()).Funcs(FuncMap{
+		"upper": strings.ToUpper,
+		"repeat": func(s string, n int) string {
+			return strings.Repeat(s, n)
+		},
+		"sum": func(nums ...float64) float64 {
+			total := 0.0
+			for _, n := range nums {
+				total += n
+			}
+			return total
+		},
+	})
+	cases := map[string]any{
+		`price * qty + 1`:            51.0,
+		`(price - 2.5) / 2 % 3`:      2.0,
+		`-price`:                     -12.5,
+		`"Hi " + user.name + "!"`:    "Hi Papyrus!",
+		`"x" + qty`:                  "x4",
+		`qty >= 4 && user.vip`:       true,
+		`!user.vip || missing.path`:  nil,
+		`empty || "默认"`:              "默认",
+		`user.name && items[1].name`: "B",
+		`missing == nil`:             true,
+		`user.name != nil`:           true,
+		`items[0]["name"] == "A"`:    true,
+		`qty > 3 ? "多" : "少"`:        "多",
+		`"b" > "a"`:                  true,
+		`upper(user.name)`:           "PAPYRUS",
+		`repeat("-", qty)`:           "----",
+		`sum(1, 2, qty)`:             7.0,
+		`items[qty - 3].name`:        "B",
+	}
+	for src, want := range cases {
+		node, err := dsl.ParseExpr(src, lexer.Position{Line: 1, Column: 1})
+		if err != nil {
+			t.Fatalf("解析 %q 失败: %v", src, err)
+		}
+		got, err := Eval(node, scope)
+		if err != nil {
+			t.Fatalf("求值 %q 失败: %v", src, err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("求值 %q 期望 %v，实际 %v", src, want, got)
+		}
+	}
+}
+
+// TestEvalErrors 验证类型错误、除零与未定义函数会返回带位置的错误。
+func TestEvalErrors(t *testing.T) {
+	cases := map[string]string{
+		`user - 1`:         "1:6:",
+		`qty / (qty - 4)`:  "1:5:",
+		`nope(1)`:          "1:1:",
+		`repeat("-", 1.5)`: "1:13:",
+		`user < 1`:         "1:6:",
+		`items[true]`:      "1:7:",
+	}
+	scope := NewScope(testData()).Funcs(FuncMap{"repeat": strings.Repeat})
+	for src, want := range cases {
+		node, err := dsl.ParseExpr(src, lexer.Position{Line: 1, Column: 1})
+		if err != nil {
+			t.Fatalf("解析 %q 失败: %v", src, err)
+		}
+		_, err = Eval(node, scope)
+		if err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Fatalf("求值 %q 期望位于 %s 的错误，实际 %v", src, want, err)
+		}
+	}
+}
+
+// TestInterpolate 验证插值会计算表达式、保留不存在的路径，并正确处理占位符中的花括号与字符串。
+func TestInterpolate(t *testing.T) {
+	data := testData()
+	cases := map[string]string{
+		`合计 ${price * qty} 元`:           "合计 50 元",
+		`${user.name}/${items[1].name}`: "Papyrus/B",
+		`${missing.name} 保留`:            "${missing.name} 保留",
+		`${"}" + user.name}`:            "}Papyrus",
+		`${user.vip ? "VIP" : "普通"}`:    "VIP",
+		`${missing} ${missing || "兜底"}`: "${missing} 兜底",
+		`未闭合 ${user.name`:               "未闭合 ${user.name",
+		`${1000000 * 3}`:                "3000000",
+	}
+	for text, want := range cases {
+		if got := Interpolate(text, data); got != want {
+			t.Fatalf("插值 %q 期望 %q，实际 %q", text, want, got)
+		}
+	}
+
+	_, err := Expand("第一行\n合计 ${price +* 2}", lexer.Position{Line: 3, Column: 10}, data)
+	if err == nil || !strings.HasPrefix(err.Error(), "4:13:") {
+		t.Fatalf("语法错误应指向占位符内的位置，实际 %v", err)
+	}
+	if got := Interpolate(`${price +* 2}`, data); got != `${price +* 2}` {
+		t.Fatalf("Interpolate 遇到语法错误应保留占位符，实际 %q", got)
+	}
+}
+
+// TestTemplateCacheBounded 验证模板缓存有容量上限，按最近使用淘汰。
+func TestTemplateCacheBounded(t *testing.T) {
+	cache := newTemplateLRU(2)
+	keys := []templateKey{{text: "a"}, {text: "b"}, {text: "c"}}
+	cache.put(keys[0], &template{})
+	cache.put(keys[1], &template{})
+	cache.get(keys[0])
+	cache.put(keys[2], &template{})
+	if cache.len() != 2 {
+		t.Fatalf("缓存容量应为 2，实际 %d", cache.len())
+	}
+	if _, ok := cache.get(keys[1]); ok {
+		t.Fatalf("最久未使用的模板应被淘汰")
+	}
+	if _, ok := cache.get(keys[0]); !ok {
+		t.Fatalf("最近使用的模板不应被淘汰")
+	}
+
+	for i := 0; i < templateCacheSize+10; i++ {
+		Interpolate(fmt.Sprintf("第 %d 条 ${price}", i), testData())
+	}
+	if n := templateCache.len(); n > templateCacheSize {
+		t.Fatalf("全局模板缓存超出容量：%d", n)
+	}
+}
+
+var noPos = lexer.Position{Line: 1, Column: 1}
+
+// TestMissingPolicy 验证三种缺失策略的输出，以及未解析绑定的收集与去重。
+func TestMissingPolicy(t *testing.T) {
+	text := `A${user.name}B${nope.x}C${default(nope.y, "-")}`
+	cases := map[MissingPolicy]string{
+		MissingKeep:  "APapyrusB${nope.x}C-",
+		MissingEmpty: "APapyrusBC-",
+		MissingError: "APapyrusBC-",
+	}
+	for policy, want := range cases {
+		scope := NewScope(testData()).Missing(policy)
+		for i := 0; i < 2; i++ {
+			got, err := Expand(text, lexer.Position{Line: 2, Column: 3}, scope.Child())
+			if err != nil {
+				t.Fatalf("%s: 求值失败: %v", policy, err)
+			}
+			if got != want {
+				t.Fatalf("%s: 期望 %q，实际 %q", policy, want, got)
+			}
+		}
+		list := scope.Unresolved()
+		if len(list) != 1 {
+			t.Fatalf("%s: 同一位置的缺失路径应只记录一次，实际 %v", policy, list)
+		}
+		u := list[0]
+		if u.Path != "nope.x" || u.Text != "${nope.x}" || u.Pos.Line != 2 || u.Pos.Column != 19 {
+			t.Fatalf("%s: 未解析绑定记录错误: %+v", policy, u)
+		}
+	}
+	if _, err := ParseMissingPolicy("drop"); err == nil {
+		t.Fatalf("未知策略应返回错误")
+	}
+}
+
+// TestExpandMarkup 验证 escape 只作用于插值结果，raw() 返回的可信片段不转义。
+func TestExpandMarkup(t *testing.T) {
+	escape := func(s string) string { return strings.ReplaceAll(s, "#", `\#`) }
+	data := map[string]any{"a": "#x", "b": "#y"}
+	got, err := ExpandMarkup(`#b[${a}] ${raw(b)} ${missing}`, noPos, data, escape)
+	if err != nil {
+		t.Fatalf("求值失败: %v", err)
+	}
+	if want := `#b[\#x] #y ${missing}`; got != want {
+		t.Fatalf("期望 %q，实际 %q", want, got)
+	}
+}
@@ -0,0 +1,378 @@
+package binding
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// 该文件实现绑定表达式的求值：dsl 包负责把 `${...}` 与命令参数解析为 AST，这里针对作用域数据求值。
+
+// FuncMap 保存表达式中可调用的函数，键为函数名。
+// 函数可接收任意数量的参数（支持可变参数），返回一个值，或返回 (值, error)。
+// 参数按函数签名转换：数字可传给 int/float 形参，任意值可传给 string 形参（按插值格式转换）。
+type FuncMap map[string]any
+
+// Eval 对表达式 AST 求值，data 可以是根数据或 *Scope。
+// 不存在的路径求值为 nil，因此 `data.summary != nil` 可用于判断字段是否存在。
+func Eval(node dsl.Expr, data any) (any, error) {
+	ev := evaluator{scope: ScopeOf(data)}
+	return ev.eval(node)
+}
+
+// evaluator 在求值过程中记录未能解析的路径，供插值判断是否保留占位符。
+type evaluator struct {
+	scope   *Scope
+	missing []dsl.Expr
+}
+
+func (ev *evaluator) eval(node dsl.Expr) (any, error) {
+	switch n := node.(type) {
+	case *dsl.LiteralExpr:
+		return n.Value, nil
+	case *dsl.IdentExpr, *dsl.MemberExpr, *dsl.IndexExpr:
+		val, ok, err := ev.lookup(node)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			ev.missing = append(ev.missing, node)
+		}
+		return val, nil
+	case *dsl.UnaryExpr:
+		val, err := ev.eval(n.Operand)
+		if err != nil {
+			return nil, err
+		}
+		if n.Op == "!" {
+			return !Truthy(val), nil
+		}
+		f, ok := toFloat(val)
+		if !ok {
+			return nil, fmt.Errorf("%s: 无法对 %s 取负", n.Pos, typeName(val))
+		}
+		return -f, nil
+	case *dsl.BinaryExpr:
+		return ev.evalBinary(n)
+	case *dsl.CondExpr:
+		cond, err := ev.eval(n.Cond)
+		if err != nil {
+			return nil, err
+		}
+		if Truthy(cond) {
+			return ev.eval(n.Then)
+		}
+		return ev.eval(n.Else)
+	case *dsl.CallExpr:
+		return ev.call(n)
+	case nil:
+		return nil, fmt.Errorf("表达式为空")
+	default:
+		return nil, fmt.Errorf("%s: 不支持的表达式 %s", node.ExprPos(), node)
+	}
+}
+
+// lookup 解析路径表达式（ident / member / index），ok 为 false 表示路径不存在。
+func (ev *evaluator) lookup(node dsl.Expr) (any, bool, error) {
+	switch n := node.(type) {
+	case *dsl.IdentExpr:
+		val, ok := ev.scope.Lookup(n.Name)
+		return val, ok, nil
+	case *dsl.MemberExpr:
+		target, ok, err := ev.target(n.Target)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		val, ok := descendMap(target, n.Name)
+		return val, ok, nil
+	case *dsl.IndexExpr:
+		target, ok, err := ev.target(n.Target)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		index, err := ev.eval(n.Index)
+		if err != nil {
+			return nil, false, err
+		}
+		if key, isKey := index.(string); isKey {
+			val, ok := descendMap(target, key)
+			return val, ok, nil
+		}
+		f, isNum := toFloat(index)
+		if !isNum || f != math.Trunc(f) {
+			return nil, false, fmt.Errorf("%s: 下标必须为整数或字符串，实际为 %s", n.Index.ExprPos(), typeName(index))
+		}
+		val, ok := descendArray(target, int(f))
+		return val, ok, nil
+	default:
+		val, err := ev.eval(node)
+		return val, err == nil, err
+	}
+}
+
+// target 计算成员访问的对象；对象本身不存在时不重复记录缺失路径。
+func (ev *evaluator) target(node dsl.Expr) (any, bool, error) {
+	switch node.(type) {
+	case *dsl.IdentExpr, *dsl.MemberExpr, *dsl.IndexExpr:
+		return ev.lookup(node)
+	}
+	val, err := ev.eval(node)
+	return val, err == nil, err
+}
+
+func (ev *evaluator) evalBinary(n *dsl.BinaryExpr) (any, error) {
+	left, err := ev.eval(n.Left)
+	if err != nil {
+		return nil, err
+	}
+	// && 与 || 短路求值并返回决定结果的操作数，便于写出 `${name || "匿名"}`
+	switch n.Op {
+	case "&&":
+		if !Truthy(left) {
+			return left, nil
+		}
+		return ev.eval(n.Right)
+	case "||":
+		if Truthy(left) {
+			return left, nil
+		}
+		return ev.eval(n.Right)
+	}
+	right, err := ev.eval(n.Right)
+	if err != nil {
+		return nil, err
+	}
+	switch n.Op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	case "<", "<=", ">", ">=":
+		return compareValues(n, left, right)
+	case "+":
+		lf, lok := toFloat(left)
+		rf, rok := toFloat(right)
+		if lok && rok {
+			return lf + rf, nil
+		}
+		_, ls := left.(string)
+		_, rs := right.(string)
+		if ls || rs {
+			return Format(left) + Format(right), nil
+		}
+		return nil, fmt.Errorf("%s: 无法计算 %s + %s", n.Pos, typeName(left), typeName(right))
+	case "-", "*", "/", "%":
+		lf, lok := toFloat(left)
+		rf, rok := toFloat(right)
+		if !lok || !rok {
+			return nil, fmt.Errorf("%s: 无法计算 %s %s %s", n.Pos, typeName(left), n.Op, typeName(right))
+		}
+		switch n.Op {
+		case "-":
+			return lf - rf, nil
+		case "*":
+			return lf * rf, nil
+		case "/":
+			if rf == 0 {
+				return nil, fmt.Errorf("%s: 除数为 0", n.Pos)
+			}
+			return lf / rf, nil
+		default:
+			if rf == 0 {
+				return nil, fmt.Errorf("%s: 除数为 0", n.Pos)
+			}
+			return math.Mod(lf, rf), nil
+		}
+	}
+	return nil, fmt.Errorf("%s: 不支持的运算符 %s", n.Pos, n.Op)
+}
+
+func compareValues(n *dsl.BinaryExpr, left, right any) (bool, error) {
+	if lf, ok := toFloat(left); ok {
+		if rf, ok := toFloat(right); ok {
+			switch n.Op {
+			case "<":
+				return lf < rf, nil
+			case "<=":
+				return lf <= rf, nil
+			case ">":
+				return lf > rf, nil
+			default:
+				return lf >= rf, nil
+			}
+		}
+	}
+	ls, lok := left.(string)
+	rs, rok := right.(string)
+	if lok && rok {
+		switch n.Op {
+		case "<":
+			return ls < rs, nil
+		case "<=":
+			return ls <= rs, nil
+		case ">":
+			return ls > rs, nil
+		default:
+			return ls >= rs, nil
+		}
+	}
+	return false, fmt.Errorf("%s: 无法比较 %s 与 %s", n.Pos, typeName(left), typeName(right))
+}
+
+func valuesEqual(left, right any) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	if lf, ok := toFloat(left); ok {
+		if rf, ok := toFloat(right); ok {
+			return lf == rf
+		}
+		return false
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+// call 调用作用域中注册的函数，参数按函数签名转换（如 float64 → int）。
+func (ev *evaluator) call(n *dsl.CallExpr) (any, error) {
+	if ev.isAggregate(n) {
+		return ev.callAggregate(n)
+	}
+	fn, ok := ev.scope.Func(n.Func)
+	if !ok {
+		return nil, fmt.Errorf("%s: 函数 %s 未定义", n.Pos, n.Func)
+	}
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func {
+		return nil, fmt.Errorf("%s: %s 不是函数", n.Pos, n.Func)
+	}
+	if ft.NumOut() == 0 || ft.NumOut() > 2 || (ft.NumOut() == 2 && ft.Out(1) != errorType) {
+		return nil, fmt.Errorf("%s: 函数 %s 的返回值应为 (值) 或 (值, error)", n.Pos, n.Func)
+	}
+	fixed := ft.NumIn()
+	if ft.IsVariadic() {
+		fixed--
+		if len(n.Args) < fixed {
+			return nil, fmt.Errorf("%s: 函数 %s 至少需要 %d 个参数，实际为 %d", n.Pos, n.Func, fixed, len(n.Args))
+		}
+	} else if len(n.Args) != fixed {
+		return nil, fmt.Errorf("%s: 函数 %s 需要 %d 个参数，实际为 %d", n.Pos, n.Func, fixed, len(n.Args))
+	}
+
+	in := make([]reflect.Value, len(n.Args))
+	for i, argExpr := range n.Args {
+		arg, err := ev.eval(argExpr)
+		if err != nil {
+			return nil, err
+		}
+		var pt reflect.Type
+		if i < fixed {
+			pt = ft.In(i)
+		} else {
+			pt = ft.In(fixed).Elem()
+		}
+		v, err := convertArg(arg, pt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: 函数 %s 的第 %d 个参数%v", argExpr.ExprPos(), n.Func, i+1, err)
+		}
+		in[i] = v
+	}
+
+	out := fv.Call(in)
+	if len(out) == 2 && !out[1].IsNil() {
+		return nil, fmt.Errorf("%s: %s: %w", n.Pos, n.Func, out[1].Interface().(error))
+	}
+	return out[0].Interface(), nil
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// convertArg 将求值结果转换为函数参数类型。
+func convertArg(arg any, pt reflect.Type) (reflect.Value, error) {
+	if arg == nil {
+		return reflect.Zero(pt), nil
+	}
+	v := reflect.ValueOf(arg)
+	if v.Type().AssignableTo(pt) {
+		return v, nil
+	}
+	if f, ok := toFloat(arg); ok {
+		switch pt.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if f != math.Trunc(f) {
+				return reflect.Value{}, fmt.Errorf("应为整数，实际为 %v", f)
+			}
+			return reflect.ValueOf(f).Convert(pt), nil
+		case reflect.Float32, reflect.Float64:
+			return reflect.ValueOf(f).Convert(pt), nil
+		}
+	}
+	if pt.Kind() == reflect.String {
+		return reflect.ValueOf(Format(arg)).Convert(pt), nil
+	}
+	return reflect.Value{}, fmt.Errorf("类型应为 %s，实际为 %s", pt, typeName(arg))
+}
+
+// Format 将值转换为插值文本：nil 输出空字符串，浮点数不使用科学计数法，
+// 实现了 encoding.TextMarshaler 或 fmt.Stringer 的值（如 time.Time）使用其文本表示。
+func Format(val any) string {
+	switch v := normalize(val).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		if text, ok := formatValue(v); ok {
+			return text
+		}
+		return fmt.Sprint(v)
+	}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case nil, bool, string:
+		return 0, false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func typeName(v any) string {
+	switch normalize(v).(type) {
+	case nil:
+		return "nil"
+	case string:
+		return "字符串"
+	case bool:
+		return "布尔值"
+	case []interface{}:
+		return "数组"
+	case map[string]interface{}:
+		return "对象"
+	}
+	if _, ok := toFloat(v); ok {
+		return "数字"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,256 @@
+package binding
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 该文件定义表达式中默认可用的内置函数。应用可通过 Scope.Funcs（或 layout.BuildOptions.Funcs）注册同名函数覆盖它们。
+
+var builtins = FuncMap{
+	"formatFloat": formatFloat,
+	"formatMoney": formatMoney,
+	"formatDate":  formatDate,
+	"upper":       strings.ToUpper,
+	"lower":       strings.ToLower,
+	"padLeft":     padLeft,
+	"padRight":    padRight,
+	"default":     defaultValue,
+	"join":        join,
+	"len":         length,
+	"raw":         raw,
+}
+
+// Builtins 返回内置函数表的副本。
+func Builtins() FuncMap {
+	out := make(FuncMap, len(builtins))
+	for name, fn := range builtins {
+		out[name] = fn
+	}
+	return out
+}
+
+// formatFloat(v, decimals) 按固定小数位输出数字，例如 formatFloat(3.14159, 2) → "3.14"。
+func formatFloat(v any, decimals int) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	f, err := numberArg(v)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(f, 'f', clampDecimals(decimals), 64), nil
+}
+
+// formatMoney(v[, symbol[, decimals]]) 输出带千分位的金额，默认保留 2 位小数，
+// 例如 formatMoney(1234.5, "¥") → "¥1,234.50"，formatMoney(-8, "$", 0) → "-$8"。
+func formatMoney(v any, opts ...any) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	f, err := numberArg(v)
+	if err != nil {
+		return "", err
+	}
+	symbol := ""
+	decimals := 2
+	if len(opts) > 2 {
+		return "", fmt.Errorf("最多接受 3 个参数")
+	}
+	if len(opts) > 0 && opts[0] != nil {
+		symbol = Format(opts[0])
+	}
+	if len(opts) > 1 {
+		d, err := numberArg(opts[1])
+		if err != nil {
+			return "", err
+		}
+		decimals = int(d)
+	}
+	decimals = clampDecimals(decimals)
+
+	sign := ""
+	if f < 0 {
+		sign = "-"
+		f = -f
+	}
+	s := strconv.FormatFloat(f, 'f', decimals, 64)
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i:]
+	}
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	if sign != "" && strings.Trim(b.String()+frac, "0.,") == "" {
+		sign = "" // 避免输出 -0.00
+	}
+	return sign + symbol + b.String() + frac, nil
+}
+
+// dateInputLayouts 为 formatDate 解析字符串日期时依次尝试的格式。
+var dateInputLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+}
+
+// formatDate(v[, layout]) 按 Go 时间格式输出日期，默认 "2006-01-02"。
+// v 可以是 time.Time、常见格式的日期字符串（RFC 3339、2006-01-02 等）或 Unix 秒数。
+func formatDate(v any, layout ...string) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	out := "2006-01-02"
+	if len(layout) > 1 {
+		return "", fmt.Errorf("最多接受 2 个参数")
+	}
+	if len(layout) == 1 && layout[0] != "" {
+		out = layout[0]
+	}
+	switch t := v.(type) {
+	case time.Time:
+		return t.Format(out), nil
+	case *time.Time:
+		if t == nil {
+			return "", nil
+		}
+		return t.Format(out), nil
+	case string:
+		if t == "" {
+			return "", nil
+		}
+		for _, in := range dateInputLayouts {
+			if parsed, err := time.Parse(in, t); err == nil {
+				return parsed.Format(out), nil
+			}
+		}
+		return "", fmt.Errorf("无法识别的日期 %q", t)
+	}
+	if f, ok := toFloat(v); ok {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC().Format(out), nil
+	}
+	return "", fmt.Errorf("无法将 %s 格式化为日期", typeName(v))
+}
+
+// padLeft(s, width[, pad]) 在左侧补齐到 width 个字符，默认使用空格。
+func padLeft(v any, width int, pad ...string) (string, error) {
+	s, fill, err := padArgs(v, width, pad)
+	if err != nil {
+		return "", err
+	}
+	return fill + s, nil
+}
+
+// padRight(s, width[, pad]) 在右侧补齐到 width 个字符，默认使用空格。
+func padRight(v any, width int, pad ...string) (string, error) {
+	s, fill, err := padArgs(v, width, pad)
+	if err != nil {
+		return "", err
+	}
+	return s + fill, nil
+}
+
+func padArgs(v any, width int, pad []string) (string, string, error) {
+	s := Format(v)
+	p := " "
+	if len(pad) > 1 {
+		return "", "", fmt.Errorf("最多接受 3 个参数")
+	}
+	if len(pad) == 1 {
+		if pad[0] == "" {
+			return "", "", fmt.Errorf("填充字符不能为空")
+		}
+		p = pad[0]
+	}
+	missing := width - utf8.RuneCountInString(s)
+	if missing <= 0 {
+		return s, "", nil
+	}
+	fill := strings.Repeat(p, missing/utf8.RuneCountInString(p)+1)
+	return s, string([]rune(fill)[:missing]), nil
+}
+
+// default(v, fallback) 在 v 为 nil 或空字符串时返回 fallback。
+func defaultValue(v, fallback any) any {
+	if v == nil {
+		return fallback
+	}
+	if s, ok := v.(string); ok && s == "" {
+		return fallback
+	}
+	return v
+}
+
+// join(list[, sep]) 用分隔符（默认 ", "）连接数组元素。
+func join(list any, sep ...string) (string, error) {
+	items, ok := Items(list)
+	if !ok {
+		return "", fmt.Errorf("参数应为数组，实际为 %s", typeName(list))
+	}
+	if len(sep) > 1 {
+		return "", fmt.Errorf("最多接受 2 个参数")
+	}
+	separator := ", "
+	if len(sep) == 1 {
+		separator = sep[0]
+	}
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = Format(item)
+	}
+	return strings.Join(parts, separator), nil
+}
+
+// len(v) 返回字符串的字符数或数组、对象的元素个数，nil 为 0。
+func length(v any) (int, error) {
+	if s, ok := normalize(v).(string); ok {
+		return utf8.RuneCountInString(s), nil
+	}
+	if n, ok := reflectLen(v); ok {
+		return n, nil
+	}
+	return 0, fmt.Errorf("无法计算 %s 的长度", typeName(v))
+}
+
+// raw(v) 将值标记为可信的标记片段，插值时不转义，例如 ${raw(data.noteMarkup)}。
+// 仅应用于受信任的数据。
+func raw(v any) Markup {
+	return Markup(Format(v))
+}
+
+// numberArg 将数字或数字字符串转换为 float64。
+func numberArg(v any) (float64, error) {
+	if f, ok := toFloat(v); ok {
+		return f, nil
+	}
+	if s, ok := v.(string); ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("参数应为数字，实际为 %s", typeName(v))
+}
+
+func clampDecimals(d int) int {
+	if d < 0 {
+		return 0
+	}
+	if d > 10 {
+		return 10
+	}
+	return d
+}
@@ -0,0 +1,73 @@
+package binding
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBuiltins 验证内置格式化、字符串与集合函数。
+func TestBuiltins(t *testing.T) {
+	data := map[string]any{
+		"total":   1234567.891,
+		"neg":     -0.001,
+		"date":    "2024-03-05T08:30:00Z",
+		"created": time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+		"tags":    []any{"a", "b", 3.0},
+		"name":    "Papyrus",
+		"blank":   "",
+	}
+	cases := map[string]string{
+		`${formatFloat(total, 1)}`:                                            "1234567.9",
+		`${formatFloat("2.5", 0)}`:                                            "2",
+		`${formatMoney(total)}`:                                               "1,234,567.89",
+		`${formatMoney(total, "¥")}`:                                          "¥1,234,567.89",
+		`${formatMoney(-1234, "$", 0)}`:                                       "-$1,234",
+		`${formatMoney(neg, "", 2)}`:                                          "0.00",
+		`${formatMoney(999.999)}`:                                             "1,000.00",
+		`${formatDate(date)}`:                                                 "2024-03-05",
+		`${formatDate(date, "02/01/2006 15:04")}`:                             "05/03/2024 08:30",
+		`${formatDate(created, "2006年1月2日")}`:                                 "2023年12月31日",
+		`${formatDate(0, "2006")}`:                                            "1970",
+		`${upper(name)}-${lower(name)}`:                                       "PAPYRUS-papyrus",
+		`[${padLeft(42, 5, "0")}|${padRight(name, 9)}|${padLeft(name, 3)}]`:   "[00042|Papyrus  |Papyrus]",
+		`${default(blank, "-")}${default(missing, "?")}${default(name, "-")}`: "-?Papyrus",
+		`${join(tags)} ${join(tags, "/")}`:                                    "a, b, 3 a/b/3",
+		`${len(tags)} ${len(name)} ${len("中文")} ${len(missing)}`:              "3 7 2 0",
+	}
+	for text, want := range cases {
+		got, err := Expand(text, noPos, data)
+		if err != nil {
+			t.Fatalf("%s 求值失败: %v", text, err)
+		}
+		if got != want {
+			t.Fatalf("%s 期望 %q，实际 %q", text, want, got)
+		}
+	}
+
+	for _, text := range []string{`${formatMoney("abc")}`, `${formatDate("yesterday")}`, `${len(total)}`, `${join(name)}`} {
+		if _, err := Expand(text, noPos, data); err == nil {
+			t.Fatalf("%s 应返回错误", text)
+		}
+	}
+}
+
+// TestCustomFuncs 验证自定义函数可覆盖内置函数，且在子作用域中可用。
+func TestCustomFuncs(t *testing.T) {
+	scope := NewScope(map[string]any{"amount": 12.0}).Funcs(FuncMap{
+		"upper": func(s string) string { return "<" + strings.ToUpper(s) + ">" },
+		"cents": func(v float64) int64 { return int64(v * 100) },
+	})
+	child := scope.Child()
+	child.Set("x", "ok")
+	got, err := Expand(`${upper(x)} ${cents(amount)} ${lower("A")}`, noPos, child)
+	if err != nil {
+		t.Fatalf("求值失败: %v", err)
+	}
+	if got != "<OK> 1200 a" {
+		t.Fatalf("自定义函数结果错误: %q", got)
+	}
+	if _, ok := Builtins()["formatMoney"]; !ok {
+		t.Fatalf("Builtins 应包含 formatMoney")
+	}
+}
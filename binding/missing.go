@@ -0,0 +1,118 @@
+package binding
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ByLCY/papyrus/dsl"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// 该文件处理插值中不存在的路径：按策略决定输出，并收集未解析的绑定供调用方诊断。
+
+// MissingPolicy 决定 `${...}` 引用的路径不存在时如何输出。
+type MissingPolicy int
+
+const (
+	MissingKeep  MissingPolicy = iota // 保留原占位符（默认）
+	MissingEmpty                      // 输出空字符串
+	MissingError                      // 输出空字符串，并由调用方在结束后报错
+)
+
+// ParseMissingPolicy 解析策略名称：keep、empty 或 error。
+func ParseMissingPolicy(name string) (MissingPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "keep":
+		return MissingKeep, nil
+	case "empty":
+		return MissingEmpty, nil
+	case "error":
+		return MissingError, nil
+	}
+	return MissingKeep, fmt.Errorf("未知的缺失绑定策略 %q（可选 keep/empty/error）", name)
+}
+
+// String 返回策略名称。
+func (p MissingPolicy) String() string {
+	switch p {
+	case MissingEmpty:
+		return "empty"
+	case MissingError:
+		return "error"
+	default:
+		return "keep"
+	}
+}
+
+// Unresolved 记录一处未能解析的绑定。
+type Unresolved struct {
+	Path string         `json:"path"` // 不存在的路径，如 user.name
+	Text string         `json:"text"` // 所在的占位符，如 ${user.name}
+	Pos  lexer.Position `json:"pos"`  // 路径在 DSL 源码中的位置
+}
+
+// String 以 `行:列: 占位符` 的形式描述未解析的绑定。
+func (u Unresolved) String() string {
+	if u.Pos.Line == 0 && u.Pos.Column == 0 {
+		return fmt.Sprintf("%s（路径 %s 不存在）", u.Text, u.Path)
+	}
+	return fmt.Sprintf("%s: %s（路径 %s 不存在）", u.Pos, u.Text, u.Path)
+}
+
+// missingReport 在作用域链中共享，同一位置的同一路径只记录一次（例如循环中的占位符）。
+type missingReport struct {
+	policy MissingPolicy
+	list   []Unresolved
+	seen   map[Unresolved]bool
+}
+
+func (r *missingReport) add(u Unresolved) {
+	if r.seen[u] {
+		return
+	}
+	if r.seen == nil {
+		r.seen = map[Unresolved]bool{}
+	}
+	r.seen[u] = true
+	r.list = append(r.list, u)
+}
+
+// Missing 设置缺失路径的处理策略并开始收集未解析的绑定；返回 s 以便链式调用。
+// 应在创建子作用域之前调用，子作用域共享同一份记录。
+func (s *Scope) Missing(policy MissingPolicy) *Scope {
+	s.missing = &missingReport{policy: policy}
+	return s
+}
+
+// Unresolved 返回已收集的未解析绑定（按出现顺序）；未调用 Missing 时返回 nil。
+func (s *Scope) Unresolved() []Unresolved {
+	if s.missing == nil {
+		return nil
+	}
+	return append([]Unresolved(nil), s.missing.list...)
+}
+
+// unresolved 记录未解析的占位符，并按策略返回输出文本。
+func (s *Scope) unresolved(raw string, path dsl.Expr) string {
+	if s.missing == nil {
+		return raw
+	}
+	s.missing.add(Unresolved{Path: path.String(), Text: raw, Pos: path.ExprPos()})
+	if s.missing.policy == MissingKeep {
+		return raw
+	}
+	return ""
+}
+
+// UnresolvedError 在 MissingError 策略下汇总所有未解析的绑定。
+type UnresolvedError struct {
+	Unresolved []Unresolved
+}
+
+func (e *UnresolvedError) Error() string {
+	parts := make([]string, len(e.Unresolved))
+	for i, u := range e.Unresolved {
+		parts[i] = u.String()
+	}
+	return fmt.Sprintf("存在 %d 处未解析的绑定: %s", len(e.Unresolved), strings.Join(parts, "; "))
+}
@@ -0,0 +1,245 @@
+package binding
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// 该文件让路径解析支持任意 Go 值：结构体（遵循 json 标签）、map[string]T、类型化切片/数组与指针。
+// map[string]interface{} 与 []interface{} 仍走快速路径，其余类型通过反射访问。
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// indirect 解开指针与接口，nil 指针返回无效值。
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// normalize 将反射得到的值转换为求值器易于处理的形式：
+// nil 指针/接口/切片/map 变为 nil，指向基础类型的指针解引用，具名字符串与布尔类型转换为 string/bool。
+// 实现了 TextMarshaler 或 Stringer 的值保持原样，由 Format 负责输出。
+func normalize(val any) any {
+	switch val.(type) {
+	case nil, string, bool, float64, int, map[string]interface{}, []interface{}:
+		return val
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+		if rv.IsNil() {
+			return nil
+		}
+	}
+	if formattable(rv.Type()) {
+		return val
+	}
+	if rv.Kind() == reflect.Pointer {
+		return normalize(rv.Elem().Interface())
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+	return val
+}
+
+// formattable 判断类型是否自带文本表示（encoding.TextMarshaler 或 fmt.Stringer）。
+func formattable(t reflect.Type) bool {
+	return t.Implements(textMarshalerType) || t.Implements(stringerType)
+}
+
+// formatValue 输出 TextMarshaler/Stringer 的文本表示，与 JSON 序列化后再绑定的结果保持一致。
+func formatValue(val any) (string, bool) {
+	if m, ok := val.(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text), true
+		}
+	}
+	if s, ok := val.(fmt.Stringer); ok {
+		return s.String(), true
+	}
+	return "", false
+}
+
+// reflectField 通过反射按名称访问 map[string]T 的键或结构体字段。
+func reflectField(current any, key string) (any, bool) {
+	v := indirect(reflect.ValueOf(current))
+	if !v.IsValid() {
+		return nil, false
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		val := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+		if !val.IsValid() {
+			return nil, false
+		}
+		return normalize(val.Interface()), true
+	case reflect.Struct:
+		index, ok := structFields(v.Type()).lookup(key)
+		if !ok {
+			return nil, false
+		}
+		field, err := v.FieldByIndexErr(index)
+		if err != nil {
+			// 经由 nil 嵌入指针访问的字段视为 nil
+			return nil, true
+		}
+		return normalize(field.Interface()), true
+	}
+	return nil, false
+}
+
+// reflectIndex 通过反射访问类型化切片/数组的元素，或以整数为键的 map。
+func reflectIndex(current any, idx int) (any, bool) {
+	v := indirect(reflect.ValueOf(current))
+	if !v.IsValid() {
+		return nil, false
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if idx < 0 || idx >= v.Len() {
+			return nil, false
+		}
+		return normalize(v.Index(idx).Interface()), true
+	case reflect.Map:
+		kt := v.Type().Key()
+		switch kt.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if idx < 0 && kt.Kind() >= reflect.Uint {
+				return nil, false
+			}
+			val := v.MapIndex(reflect.ValueOf(idx).Convert(kt))
+			if !val.IsValid() {
+				return nil, false
+			}
+			return normalize(val.Interface()), true
+		}
+	}
+	return nil, false
+}
+
+// reflectItems 将类型化切片/数组转换为元素列表。
+func reflectItems(val any) ([]any, bool) {
+	v := indirect(reflect.ValueOf(val))
+	if !v.IsValid() {
+		return nil, true
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return nil, false // []byte 视为标量
+		}
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = normalize(v.Index(i).Interface())
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// reflectLen 返回集合或字符串的长度，不支持的类型返回 false。
+func reflectLen(val any) (int, bool) {
+	v := indirect(reflect.ValueOf(val))
+	if !v.IsValid() {
+		return 0, true
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len(), true
+	}
+	return 0, false
+}
+
+// fieldSet 记录结构体可访问的字段：精确名称优先，其次不区分大小写（与 encoding/json 一致）。
+type fieldSet struct {
+	exact map[string][]int
+	fold  map[string][]int
+}
+
+func (fs *fieldSet) lookup(key string) ([]int, bool) {
+	if index, ok := fs.exact[key]; ok {
+		return index, true
+	}
+	index, ok := fs.fold[strings.ToLower(key)]
+	return index, ok
+}
+
+var fieldCache sync.Map // reflect.Type → *fieldSet
+
+// structFields 计算结构体的字段表：json 标签优先于字段名，`json:"-"` 的字段被忽略，
+// 未加标签的嵌入结构体字段会被提升（外层字段优先）。
+func structFields(t reflect.Type) *fieldSet {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(*fieldSet)
+	}
+	fs := &fieldSet{exact: map[string][]int{}, fold: map[string][]int{}}
+	collectFields(t, nil, fs, map[reflect.Type]bool{})
+	fieldCache.Store(t, fs)
+	return fs
+}
+
+func collectFields(t reflect.Type, prefix []int, fs *fieldSet, visited map[reflect.Type]bool) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, f)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		index := append(append([]int{}, prefix...), i)
+		if _, ok := fs.exact[name]; !ok {
+			fs.exact[name] = index
+		}
+		if _, ok := fs.fold[strings.ToLower(name)]; !ok {
+			fs.fold[strings.ToLower(name)] = index
+		}
+	}
+	// 嵌入结构体在本层字段之后处理，保证外层同名字段优先
+	for _, f := range embedded {
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		collectFields(ft, append(append([]int{}, prefix...), f.Index...), fs, visited)
+	}
+}
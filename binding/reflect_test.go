@@ -0,0 +1,92 @@
+package binding
+
+import (
+	"testing"
+	"time"
+)
+
+type testMoney int64
+
+func (m testMoney) String() string { return "¥" + Format(float64(m)/100) }
+
+type testStatus string
+
+type testAudit struct {
+	CreatedBy string `json:"createdBy"`
+	Note      *string
+}
+
+type testLine struct {
+	Name   string    `json:"name"`
+	Qty    int       `json:"qty,omitempty"`
+	Amount testMoney `json:"amount"`
+	secret string
+}
+
+type testInvoice struct {
+	testAudit
+	No       string                 `json:"invoiceNo"`
+	Status   testStatus             `json:"status"`
+	Issued   time.Time              `json:"issued"`
+	Lines    []testLine             `json:"lines"`
+	Tags     [2]string              `json:"tags"`
+	Extra    map[string]float64     `json:"extra"`
+	ByID     map[int]*testLine      `json:"byId"`
+	Customer *struct{ Name string } `json:"customer"`
+	Hidden   string                 `json:"-"`
+	Paid     *bool
+}
+
+// TestStructBinding 验证路径解析支持结构体（json 标签）、类型化 map/切片/数组、指针以及 Stringer/TextMarshaler。
+func TestStructBinding(t *testing.T) {
+	paid := true
+	inv := &testInvoice{
+		testAudit: testAudit{CreatedBy: "bot"},
+		No:        "INV-1",
+		Status:    "open",
+		Issued:    time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+		Lines: []testLine{
+			{Name: "设计", Qty: 2, Amount: 12050, secret: "x"},
+			{Name: "开发", Qty: 1, Amount: 800000},
+		},
+		Tags:   [2]string{"a", "b"},
+		Extra:  map[string]float64{"tax": 0.13},
+		ByID:   map[int]*testLine{7: {Name: "seven"}},
+		Hidden: "hidden",
+		Paid:   &paid,
+	}
+	cases := map[string]string{
+		`${invoiceNo}/${data.invoiceNo}`:             "INV-1/INV-1",
+		`${createdBy} ${NOTE}`:                       "bot ",
+		`${status == "open"}`:                        "true",
+		`${issued}`:                                  "2024-05-06T07:08:09Z",
+		`${formatDate(issued, "2006/01/02")}`:        "2024/05/06",
+		`${lines[1].name} x${lines[0].qty * 2}`:      "开发 x4",
+		`${lines[0].amount}`:                         "¥120.5",
+		`${lines[0].Amount}`:                         "¥120.5",
+		`${lines[0].secret}|${Hidden}`:               "${lines[0].secret}|${Hidden}",
+		`${tags[1]} ${len(tags)} ${join(tags, "+")}`: "b 2 a+b",
+		`${extra.tax * 100}`:                         "13",
+		`${byId[7].name}`:                            "seven",
+		`${customer == nil} ${customer.Name}`:        "true ${customer.Name}",
+		`${Paid ? "已付" : "未付"}`:                      "已付",
+		`${len(lines)} ${lines ? "有" : "无"}`:         "2 有",
+	}
+	for text, want := range cases {
+		got, err := Expand(text, noPos, inv)
+		if err != nil {
+			t.Fatalf("%s 求值失败: %v", text, err)
+		}
+		if got != want {
+			t.Fatalf("%s 期望 %q，实际 %q", text, want, got)
+		}
+	}
+
+	items, ok := Items(inv.Lines)
+	if !ok || len(items) != 2 {
+		t.Fatalf("Items 应支持类型化切片，实际 %v %v", items, ok)
+	}
+	if Truthy((*testInvoice)(nil)) || Truthy([]testLine{}) || !Truthy(inv) {
+		t.Fatalf("Truthy 对指针与类型化切片的判断错误")
+	}
+}
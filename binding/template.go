@@ -0,0 +1,211 @@
+package binding
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+
+	"github.com/ByLCY/papyrus/dsl"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// 该文件负责 `${...}` 插值文本的预编译：文本按占位符切分为片段，表达式只解析一次并缓存。
+
+// template 是预编译的插值文本，由字面量与表达式片段交替组成。
+type template struct {
+	parts []templatePart
+}
+
+// templatePart 为 literal 或表达式片段；表达式片段保留原始占位符，路径不存在时原样输出。
+type templatePart struct {
+	literal string
+	raw     string
+	expr    dsl.Expr
+	err     error
+}
+
+type templateKey struct {
+	text string
+	pos  lexer.Position
+}
+
+// templateCacheSize 为预编译模板缓存的容量。Interpolate/Expand 可能收到逐条记录不同的文本，
+// 缓存按最近使用淘汰，避免长期运行的服务中无限增长。
+const templateCacheSize = 1024
+
+var templateCache = newTemplateLRU(templateCacheSize)
+
+// templateLRU 是按最近使用淘汰的模板缓存，可并发访问。
+type templateLRU struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // 元素为 *templateEntry，最近使用的在前
+	items map[templateKey]*list.Element
+}
+
+type templateEntry struct {
+	key templateKey
+	tpl *template
+}
+
+func newTemplateLRU(size int) *templateLRU {
+	return &templateLRU{size: size, order: list.New(), items: map[templateKey]*list.Element{}}
+}
+
+func (c *templateLRU) get(key templateKey) (*template, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*templateEntry).tpl, true
+}
+
+func (c *templateLRU) put(key templateKey, tpl *template) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*templateEntry).tpl = tpl
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&templateEntry{key: key, tpl: tpl})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*templateEntry).key)
+	}
+}
+
+func (c *templateLRU) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// compileTemplate 切分 text 中的 `${...}` 占位符并解析其中的表达式。
+// 占位符内可包含字符串与花括号，例如 `${join(tags, "}")}`；缺少右花括号时按普通文本处理。
+func compileTemplate(text string, pos lexer.Position) *template {
+	key := templateKey{text: text, pos: pos}
+	if cached, ok := templateCache.get(key); ok {
+		return cached
+	}
+	tpl := &template{}
+	rest := text
+	for rest != "" {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			break
+		}
+		end := matchBrace(rest, start+2)
+		if end < 0 {
+			break
+		}
+		if start > 0 {
+			tpl.parts = append(tpl.parts, templatePart{literal: rest[:start]})
+		}
+		exprPos := advancePos(pos, rest[:start+2])
+		node, err := dsl.ParseExpr(rest[start+2:end], exprPos)
+		tpl.parts = append(tpl.parts, templatePart{raw: rest[start : end+1], expr: node, err: err})
+		pos = advancePos(pos, rest[:end+1])
+		rest = rest[end+1:]
+	}
+	if rest != "" {
+		tpl.parts = append(tpl.parts, templatePart{literal: rest})
+	}
+	templateCache.put(key, tpl)
+	return tpl
+}
+
+// execute 依次输出片段。strict 为 true 时返回表达式错误，否则出错的占位符保持原样。
+// escape 非空时用于转义表达式的值，模板中的字面量与保留的占位符不受影响。
+func (t *template) execute(scope *Scope, strict bool, escape func(string) string) (string, error) {
+	var b strings.Builder
+	for _, part := range t.parts {
+		if part.expr == nil && part.err == nil {
+			b.WriteString(part.literal)
+			continue
+		}
+		if part.err != nil {
+			if strict {
+				return "", part.err
+			}
+			b.WriteString(part.raw)
+			continue
+		}
+		ev := evaluator{scope: scope}
+		val, err := ev.eval(part.expr)
+		if err != nil {
+			if strict {
+				return "", err
+			}
+			b.WriteString(part.raw)
+			continue
+		}
+		if val == nil && len(ev.missing) > 0 {
+			b.WriteString(scope.unresolved(part.raw, ev.missing[0]))
+			continue
+		}
+		text := Format(val)
+		if _, trusted := val.(Markup); !trusted && escape != nil {
+			text = escape(text)
+		}
+		b.WriteString(text)
+	}
+	return b.String(), nil
+}
+
+// matchBrace 返回与 `${` 对应的右花括号下标，跳过字符串字面量与嵌套的花括号。
+func matchBrace(s string, from int) int {
+	depth := 0
+	for i := from; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\'':
+			for i++; i < len(s) && s[i] != c; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// advancePos 返回 pos 越过 s 之后的位置。
+func advancePos(pos lexer.Position, s string) lexer.Position {
+	pos.Offset += len(s)
+	for _, r := range s {
+		if r == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else {
+			pos.Column++
+		}
+	}
+	return pos
+}
+
+// Placeholders 返回 text 中每个 `${...}` 占位符解析出的表达式（按出现顺序），
+// 供静态分析使用（如推断模板所需的数据结构）。遇到语法错误时返回该错误。
+func Placeholders(text string, pos lexer.Position) ([]dsl.Expr, error) {
+	tpl := compileTemplate(text, pos)
+	var out []dsl.Expr
+	for _, part := range tpl.parts {
+		if part.err != nil {
+			return nil, part.err
+		}
+		if part.expr != nil {
+			out = append(out, part.expr)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,620 @@
+package dsl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// Expr is a node of a parsed binding expression, as used by `${...}`
+// interpolation, control statements and assignment values.
+//
+// Grammar (lowest to highest precedence):
+//
+//	expr    = or ( "?" expr ":" expr )? ;
+//	or      = and ( "||" and )* ;
+//	and     = eq ( "&&" eq )* ;
+//	eq      = cmp ( ( "==" | "!=" ) cmp )* ;
+//	cmp     = add ( ( "<" | "<=" | ">" | ">=" ) add )* ;
+//	add     = mul ( ( "+" | "-" ) mul )* ;
+//	mul     = unary ( ( "*" | "/" | "%" ) unary )* ;
+//	unary   = ( "!" | "-" ) unary | postfix ;
+//	postfix = primary ( "." ident | "[" expr "]" | "(" args? ")" )* ;
+//	primary = number | string | "true" | "false" | "nil" | "null" | ident | "(" expr ")" ;
+type Expr interface {
+	// ExprPos returns the position of the token that starts the node.
+	ExprPos() lexer.Position
+	// String renders the node back to source form.
+	String() string
+}
+
+// LiteralExpr is a number (float64), string, bool or nil constant.
+type LiteralExpr struct {
+	Pos   lexer.Position
+	Value any
+}
+
+// IdentExpr references a variable or a top-level data field.
+type IdentExpr struct {
+	Pos  lexer.Position
+	Name string
+}
+
+// MemberExpr is a field access: Target.Name.
+type MemberExpr struct {
+	Pos    lexer.Position
+	Target Expr
+	Name   string
+}
+
+// IndexExpr is an index access: Target[Index].
+type IndexExpr struct {
+	Pos    lexer.Position
+	Target Expr
+	Index  Expr
+}
+
+// CallExpr calls a registered function by name.
+type CallExpr struct {
+	Pos  lexer.Position
+	Func string
+	Args []Expr
+}
+
+// UnaryExpr is a prefix operation ("!" or "-").
+type UnaryExpr struct {
+	Pos     lexer.Position
+	Op      string
+	Operand Expr
+}
+
+// BinaryExpr is an infix operation.
+type BinaryExpr struct {
+	Pos   lexer.Position
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+// CondExpr is the ternary operator: Cond ? Then : Else.
+type CondExpr struct {
+	Pos  lexer.Position
+	Cond Expr
+	Then Expr
+	Else Expr
+}
+
+func (e *LiteralExpr) ExprPos() lexer.Position { return e.Pos }
+func (e *IdentExpr) ExprPos() lexer.Position   { return e.Pos }
+func (e *MemberExpr) ExprPos() lexer.Position  { return e.Pos }
+func (e *IndexExpr) ExprPos() lexer.Position   { return e.Pos }
+func (e *CallExpr) ExprPos() lexer.Position    { return e.Pos }
+func (e *UnaryExpr) ExprPos() lexer.Position   { return e.Pos }
+func (e *BinaryExpr) ExprPos() lexer.Position  { return e.Pos }
+func (e *CondExpr) ExprPos() lexer.Position    { return e.Pos }
+
+func (e *LiteralExpr) String() string {
+	switch v := e.Value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (e *IdentExpr) String() string  { return e.Name }
+func (e *MemberExpr) String() string { return e.Target.String() + "." + e.Name }
+func (e *IndexExpr) String() string  { return e.Target.String() + "[" + e.Index.String() + "]" }
+func (e *UnaryExpr) String() string  { return e.Op + e.Operand.String() }
+
+func (e *CallExpr) String() string {
+	args := make([]string, len(e.Args))
+	for i, a := range e.Args {
+		args[i] = a.String()
+	}
+	return e.Func + "(" + strings.Join(args, ", ") + ")"
+}
+
+func (e *BinaryExpr) String() string {
+	return "(" + e.Left.String() + " " + e.Op + " " + e.Right.String() + ")"
+}
+
+func (e *CondExpr) String() string {
+	return "(" + e.Cond.String() + " ? " + e.Then.String() + " : " + e.Else.String() + ")"
+}
+
+// ParseExpr parses an expression written inside `${...}`.
+// pos is the position of the first character of src and is used to report errors.
+func ParseExpr(src string, pos lexer.Position) (Expr, error) {
+	toks, err := scanExpr(src, pos)
+	if err != nil {
+		return nil, err
+	}
+	return parseExprTokens(toks, pos)
+}
+
+// ParseLexemes parses command arguments or assignment tokens as an expression.
+// Adjacent symbols are merged into two-character operators (eg. `!` `=` → `!=`).
+func ParseLexemes(parts []*Lexeme) (Expr, error) {
+	var pos lexer.Position
+	if len(parts) > 0 {
+		pos = parts[0].Pos
+	}
+	toks, err := lexemeTokens(parts)
+	if err != nil {
+		return nil, err
+	}
+	return parseExprTokens(toks, pos)
+}
+
+type exprTokenKind int
+
+const (
+	exprEOF exprTokenKind = iota
+	exprLiteral
+	exprIdent
+	exprOp
+)
+
+type exprToken struct {
+	kind  exprTokenKind
+	text  string
+	value any
+	pos   lexer.Position
+}
+
+var exprOperators = []string{
+	"==", "!=", "<=", ">=", "&&", "||",
+	"!", "<", ">", "+", "-", "*", "/", "%", "(", ")", "[", "]", ".", ",", "?", ":",
+}
+
+// PathLen returns how many leading lexemes of args form a path or call
+// expression such as `data.items`, `rows[0].lines` or `sortBy(items, "qty")`.
+// Commands use it to separate a data source from the attributes that follow
+// it (eg. `table data.items width 100%`). It returns 0 if args does not start
+// with an identifier.
+func PathLen(args []*Lexeme) int {
+	if len(args) == 0 || args[0].Type != "Ident" {
+		return 0
+	}
+	i := 1
+	for i < len(args) && args[i].Type == "Symbol" {
+		switch args[i].Value {
+		case ".":
+			if i+1 >= len(args) || args[i+1].Type != "Ident" {
+				return i
+			}
+			i += 2
+		case "[", "(":
+			end := closingLexeme(args, i)
+			if end < 0 {
+				return i
+			}
+			i = end + 1
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// SourceArgs splits arguments of the form `[name in] source key value ...`,
+// as used by commands iterating a data source (eg. `table line in data.items
+// width 100%`). The source must be followed by complete key/value pairs, so
+// plain attribute lists such as `columns 3 width 100%` yield a nil source and
+// rest equal to args. name is empty when the `name in` prefix is omitted.
+func SourceArgs(args []*Lexeme) (name string, source, rest []*Lexeme) {
+	from := 0
+	if len(args) >= 3 && args[0].Type == "Ident" && args[1].Type == "Ident" && args[1].Value == "in" {
+		name = args[0].Value
+		from = 2
+	}
+	n := PathLen(args[from:])
+	if n == 0 || (len(args)-from-n)%2 != 0 {
+		return "", nil, args
+	}
+	return name, args[from : from+n], args[from+n:]
+}
+
+// closingLexeme returns the index of the bracket closing args[open], or -1.
+func closingLexeme(args []*Lexeme, open int) int {
+	depth := 0
+	for i := open; i < len(args); i++ {
+		if args[i].Type != "Symbol" {
+			continue
+		}
+		switch args[i].Value {
+		case "[", "(":
+			depth++
+		case "]", ")":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// scanExpr tokenizes the source of a `${...}` expression.
+func scanExpr(src string, base lexer.Position) ([]exprToken, error) {
+	var toks []exprToken
+	pos := base
+	advance := func(s string) {
+		for _, r := range s {
+			pos.Offset += utf8.RuneLen(r)
+			if r == '\n' {
+				pos.Line++
+				pos.Column = 1
+			} else {
+				pos.Column++
+			}
+		}
+	}
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		start := pos
+		switch {
+		case unicode.IsSpace(r):
+			advance(src[i : i+size])
+			i += size
+		case r >= '0' && r <= '9':
+			j := i
+			for j < len(src) && src[j] >= '0' && src[j] <= '9' {
+				j++
+			}
+			if j+1 < len(src) && src[j] == '.' && src[j+1] >= '0' && src[j+1] <= '9' {
+				j++
+				for j < len(src) && src[j] >= '0' && src[j] <= '9' {
+					j++
+				}
+			}
+			f, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, participle.Errorf(start, "invalid number %q", src[i:j])
+			}
+			toks = append(toks, exprToken{kind: exprLiteral, text: src[i:j], value: f, pos: start})
+			advance(src[i:j])
+			i = j
+		case r == '"' || r == '\'':
+			j := i + 1
+			var b strings.Builder
+			closed := false
+			for j < len(src) {
+				c := src[j]
+				if c == '\\' && j+1 < len(src) {
+					switch src[j+1] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					case 'r':
+						b.WriteByte('\r')
+					default:
+						b.WriteByte(src[j+1])
+					}
+					j += 2
+					continue
+				}
+				if rune(c) == r {
+					closed = true
+					j++
+					break
+				}
+				b.WriteByte(c)
+				j++
+			}
+			if !closed {
+				return nil, participle.Errorf(start, "unterminated string")
+			}
+			toks = append(toks, exprToken{kind: exprLiteral, text: src[i:j], value: b.String(), pos: start})
+			advance(src[i:j])
+			i = j
+		case r == '_' || unicode.IsLetter(r):
+			j := i + size
+			for j < len(src) {
+				c, n := utf8.DecodeRuneInString(src[j:])
+				if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+					break
+				}
+				j += n
+			}
+			toks = append(toks, identToken(src[i:j], start))
+			advance(src[i:j])
+			i = j
+		default:
+			op := ""
+			for _, candidate := range exprOperators {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, participle.Errorf(start, "unexpected character %q", r)
+			}
+			toks = append(toks, exprToken{kind: exprOp, text: op, pos: start})
+			advance(op)
+			i += len(op)
+		}
+	}
+	return append(toks, exprToken{kind: exprEOF, pos: pos}), nil
+}
+
+// lexemeTokens converts DSL lexemes to expression tokens.
+func lexemeTokens(parts []*Lexeme) ([]exprToken, error) {
+	var toks []exprToken
+	end := lexer.Position{}
+	for i := 0; i < len(parts); i++ {
+		lx := parts[i]
+		end = lx.Pos
+		end.Offset += len(lx.Raw)
+		end.Column += utf8.RuneCountInString(lx.Raw)
+		switch lx.Type {
+		case "String", "Color":
+			toks = append(toks, exprToken{kind: exprLiteral, text: lx.Raw, value: lx.Value, pos: lx.Pos})
+		case "Number":
+			// numbers with a unit suffix (eg. 10mm) stay strings
+			var val any = lx.Value
+			if f, err := strconv.ParseFloat(lx.Value, 64); err == nil {
+				val = f
+			}
+			toks = append(toks, exprToken{kind: exprLiteral, text: lx.Raw, value: val, pos: lx.Pos})
+		case "Ident":
+			toks = append(toks, identToken(lx.Value, lx.Pos))
+		case "Symbol":
+			op := lx.Value
+			if i+1 < len(parts) {
+				next := parts[i+1]
+				pair := op + next.Value
+				adjacent := next.Type == "Symbol" && next.Pos.Offset == lx.Pos.Offset+len(lx.Raw)
+				switch pair {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					if adjacent {
+						op = pair
+						i++
+						end.Offset++
+						end.Column++
+					}
+				}
+			}
+			if op == "&" || op == "|" || op == "=" || op == ";" {
+				return nil, participle.Errorf(lx.Pos, "unexpected %q", op)
+			}
+			toks = append(toks, exprToken{kind: exprOp, text: op, pos: lx.Pos})
+		default:
+			return nil, participle.Errorf(lx.Pos, "unexpected %q", lx.Raw)
+		}
+	}
+	return append(toks, exprToken{kind: exprEOF, pos: end}), nil
+}
+
+func identToken(name string, pos lexer.Position) exprToken {
+	switch name {
+	case "true":
+		return exprToken{kind: exprLiteral, text: name, value: true, pos: pos}
+	case "false":
+		return exprToken{kind: exprLiteral, text: name, value: false, pos: pos}
+	case "nil", "null":
+		return exprToken{kind: exprLiteral, text: name, value: nil, pos: pos}
+	}
+	return exprToken{kind: exprIdent, text: name, pos: pos}
+}
+
+// exprParser is a precedence-climbing parser over expression tokens.
+type exprParser struct {
+	toks []exprToken
+	i    int
+}
+
+func parseExprTokens(toks []exprToken, pos lexer.Position) (Expr, error) {
+	p := &exprParser{toks: toks}
+	if p.peek().kind == exprEOF {
+		return nil, participle.Errorf(pos, "empty expression")
+	}
+	node, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != exprEOF {
+		return nil, participle.Errorf(tok.pos, "unexpected %q", tok.text)
+	}
+	return node, nil
+}
+
+func (p *exprParser) peek() exprToken { return p.toks[p.i] }
+
+func (p *exprParser) next() exprToken {
+	tok := p.toks[p.i]
+	if tok.kind != exprEOF {
+		p.i++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the given operators.
+func (p *exprParser) accept(ops ...string) (exprToken, bool) {
+	tok := p.peek()
+	if tok.kind != exprOp {
+		return tok, false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.i++
+			return tok, true
+		}
+	}
+	return tok, false
+}
+
+func (p *exprParser) expect(op string) error {
+	if _, ok := p.accept(op); ok {
+		return nil
+	}
+	tok := p.peek()
+	if tok.kind == exprEOF {
+		return participle.Errorf(tok.pos, "expected %q but expression ended", op)
+	}
+	return participle.Errorf(tok.pos, "expected %q but got %q", op, tok.text)
+}
+
+func (p *exprParser) parseExpr() (Expr, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	tok, ok := p.accept("?")
+	if !ok {
+		return cond, nil
+	}
+	then, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &CondExpr{Pos: tok.pos, Cond: cond, Then: then, Else: els}, nil
+}
+
+// binaryLevels lists infix operators from lowest to highest precedence.
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) parseBinary(level int) (Expr, error) {
+	if level == len(binaryLevels) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.accept(binaryLevels[level]...)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Pos: tok.pos, Op: tok.text, Left: left, Right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (Expr, error) {
+	if tok, ok := p.accept("!", "-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Pos: tok.pos, Op: tok.text, Operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *exprParser) parsePostfix() (Expr, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if tok, ok := p.accept("."); ok {
+			name := p.next()
+			if name.kind != exprIdent && !(name.kind == exprLiteral && isKeyword(name.text)) {
+				return nil, participle.Errorf(name.pos, "expected field name after %q", tok.text)
+			}
+			node = &MemberExpr{Pos: node.ExprPos(), Target: node, Name: name.text}
+			continue
+		}
+		if _, ok := p.accept("["); ok {
+			index, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			node = &IndexExpr{Pos: node.ExprPos(), Target: node, Index: index}
+			continue
+		}
+		if tok, ok := p.accept("("); ok {
+			ident, isIdent := node.(*IdentExpr)
+			if !isIdent {
+				return nil, participle.Errorf(tok.pos, "only named functions can be called, got %s", node)
+			}
+			var args []Expr
+			if _, ok := p.accept(")"); !ok {
+				for {
+					arg, err := p.parseExpr()
+					if err != nil {
+						return nil, err
+					}
+					args = append(args, arg)
+					if _, ok := p.accept(","); ok {
+						continue
+					}
+					if err := p.expect(")"); err != nil {
+						return nil, err
+					}
+					break
+				}
+			}
+			node = &CallExpr{Pos: ident.Pos, Func: ident.Name, Args: args}
+			continue
+		}
+		return node, nil
+	}
+}
+
+func (p *exprParser) parsePrimary() (Expr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case exprLiteral:
+		return &LiteralExpr{Pos: tok.pos, Value: tok.value}, nil
+	case exprIdent:
+		return &IdentExpr{Pos: tok.pos, Name: tok.text}, nil
+	case exprOp:
+		if tok.text == "(" {
+			node, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+		return nil, participle.Errorf(tok.pos, "unexpected %q", tok.text)
+	default:
+		return nil, participle.Errorf(tok.pos, "unexpected end of expression")
+	}
+}
+
+// isKeyword reports whether a literal token may still be used as a field name (eg. data.null).
+func isKeyword(text string) bool {
+	switch text {
+	case "true", "false", "nil", "null":
+		return true
+	}
+	return false
+}
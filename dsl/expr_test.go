@@ -0,0 +1,141 @@
+package dsl_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ByLCY/papyrus/dsl"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+func TestParseExpr(t *testing.T) {
+	cases := map[string]string{
+		`a + b * 2`:                                    `(a + (b * 2))`,
+		`!data.paid && total >= 10 || vip`:             `((!data.paid && (total >= 10)) || vip)`,
+		`items[0].name + " x"`:                         `(items[0].name + " x")`,
+		`qty > 1 ? "many" : qty == 1 ? "one" : "none"`: `((qty > 1) ? "many" : ((qty == 1) ? "one" : "none"))`,
+		`formatFloat(-(a - b) / 2, 2)`:                 `formatFloat((-(a - b) / 2), 2)`,
+		`user['first name'] != nil`:                    `(user["first name"] != nil)`,
+		`客户.名称`:                                        `客户.名称`,
+	}
+	for src, want := range cases {
+		node, err := dsl.ParseExpr(src, lexer.Position{Line: 1, Column: 1})
+		if err != nil {
+			t.Fatalf("parse %q failed: %v", src, err)
+		}
+		if got := node.String(); got != want {
+			t.Fatalf("parse %q: expected %s, got %s", src, want, got)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	cases := map[string]string{
+		`a +`:       "1:8:",
+		`a b`:       "1:7:",
+		`(a`:        "1:7:",
+		`"open`:     "1:5:",
+		`a.b(1)`:    "1:8:",
+		`a ? b`:     "1:10:",
+		`price @ 2`: "1:11:",
+	}
+	for src, want := range cases {
+		_, err := dsl.ParseExpr(src, lexer.Position{Line: 1, Column: 5, Offset: 4})
+		if err == nil {
+			t.Fatalf("parse %q: expected error", src)
+		}
+		if !strings.HasPrefix(err.Error(), want) {
+			t.Fatalf("parse %q: expected error at %s, got %v", src, want, err)
+		}
+	}
+}
+
+func TestExpressionLexemes(t *testing.T) {
+	doc, err := dsl.ParseString(`doc T v1 {
+  page A4 {
+    flow {
+      if data.total != nil && data.total >= 100 || !data.items {
+        text { "x" }
+      }
+      let label = data.vip ? "VIP" : "-"
+    }
+  }
+  resources {
+    style Body {
+      field: item.price * item.qty
+    }
+  }
+}`)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	flow := doc.Sections[0].Page.Block.Statements[0].Command
+	ifCmd := flow.Block.Statements[0].Command
+	node, err := ifCmd.Expr(0)
+	if err != nil {
+		t.Fatalf("if condition failed to parse: %v", err)
+	}
+	if got, want := node.String(), "(((data.total != nil) && (data.total >= 100)) || !data.items)"; got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+	again, _ := ifCmd.Expr(0)
+	if again != node {
+		t.Fatalf("expected command expression to be cached")
+	}
+
+	letCmd := flow.Block.Statements[1].Command
+	node, err = letCmd.Expr(2)
+	if err != nil {
+		t.Fatalf("let value failed to parse: %v", err)
+	}
+	if got, want := node.String(), `(data.vip ? "VIP" : "-")`; got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+
+	style := doc.Sections[1].Resources.Block.Statements[0].Command
+	field := style.Block.Statements[0].Assignment
+	node, err = field.Value.Expr.AST()
+	if err != nil {
+		t.Fatalf("assignment expression failed to parse: %v", err)
+	}
+	if got, want := node.String(), "(item.price * item.qty)"; got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+
+	if _, err := (&dsl.Command{Name: "if"}).Expr(0); err == nil {
+		t.Fatalf("expected error for missing expression")
+	}
+}
+
+func TestSourceArgs(t *testing.T) {
+	doc, err := dsl.ParseString(`doc T v1 {
+  page A4 {
+    table data.items width 100% { }
+    table line in rows[0].lines row-gap 2mm { }
+    table sortBy(data.items, "qty").top { }
+    table columns 3 width 100% { }
+    table 3 { }
+  }
+}`)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	tests := []struct {
+		name   string
+		source int
+		rest   int
+	}{
+		{"", 3, 2},
+		{"line", 6, 2},
+		{"", 10, 0},
+		{"", 0, 4},
+		{"", 0, 1},
+	}
+	for i, stmt := range doc.Sections[0].Page.Block.Statements {
+		name, source, rest := dsl.SourceArgs(stmt.Command.Args)
+		want := tests[i]
+		if name != want.name || len(source) != want.source || len(rest) != want.rest {
+			t.Errorf("table %d: expected (%q, %d, %d), got (%q, %d, %d)", i, want.name, want.source, want.rest, name, len(source), len(rest))
+		}
+	}
+}
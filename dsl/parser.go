@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"sync"
 
 	"github.com/alecthomas/participle/v2"
 	"github.com/alecthomas/participle/v2/lexer"
@@ -20,7 +21,7 @@ var (
 		{Name: "Number", Pattern: `(?:\d+\.\d+|\d+)(?:pt|mm|cm|in|%|x)?`},
 		{Name: "String", Pattern: `"(?:\\.|[^"])*"`},
 		{Name: "Ident", Pattern: `[A-Za-z_][A-Za-z0-9_-]*`},
-		{Name: "Symbol", Pattern: `[][(),.=+\-*/%<>!?;:]`},
+		{Name: "Symbol", Pattern: `[][(),.=+\-*/%<>!?;:&|]`},
 		{Name: "LBrace", Pattern: `{`},
 		{Name: "RBrace", Pattern: `}`},
 	})
@@ -124,15 +125,49 @@ type Command struct {
 	Name  string         `parser:"@Ident"`
 	Args  []*Lexeme      `parser:"@@*"`
 	Block *Block         `parser:"( Newline* @@ )?"`
+
+	exprMu sync.Mutex
+	exprs  map[int]parsedExpr
+}
+
+type parsedExpr struct {
+	node Expr
+	err  error
+}
+
+// Expr parses Args[from:] as an expression (eg. the condition of `if` or the
+// value of `let x = ...`). The result is cached on the command, so statements
+// evaluated repeatedly inside loops are parsed only once.
+func (c *Command) Expr(from int) (Expr, error) {
+	c.exprMu.Lock()
+	defer c.exprMu.Unlock()
+	if parsed, ok := c.exprs[from]; ok {
+		return parsed.node, parsed.err
+	}
+	var args []*Lexeme
+	if from < len(c.Args) {
+		args = c.Args[from:]
+	}
+	node, err := ParseLexemes(args)
+	if err != nil && len(args) == 0 {
+		err = participle.Errorf(c.Pos, "%s: missing expression", c.Name)
+	}
+	if c.exprs == nil {
+		c.exprs = map[int]parsedExpr{}
+	}
+	c.exprs[from] = parsedExpr{node: node, err: err}
+	return node, err
 }
 
 // TextLiteral encapsulates raw string statements within blocks.
 type TextLiteral struct {
-	Value StringLiteral `parser:"@String"`
+	Pos   lexer.Position `parser:"" json:"-"`
+	Value StringLiteral  `parser:"@String"`
 }
 
 // Value represents generic property values.
 type Value struct {
+	Pos    lexer.Position `parser:"" json:"-"`
 	String *StringLiteral `parser:"  @String"`
 	Number *string        `parser:"| @Number"`
 	Color  *string        `parser:"| @Color"`
@@ -151,9 +186,19 @@ type InlineObject struct {
 	Entries []*Assignment `parser:"'{' Newline* ( @@ Newline* ( (';' | Newline+) Newline* @@ Newline* )* )? Newline* '}'"`
 }
 
-// Expression records raw tokens for later evaluation.
+// Expression records raw tokens together with the expression tree parsed from them.
 type Expression struct {
 	Parts []*Lexeme
+
+	node Expr
+	err  error
+}
+
+// AST returns the parsed expression tree. Values that are not valid
+// expressions (eg. `font: Inter Bold`) still parse as a Value; the syntax
+// error is only reported when the expression is evaluated.
+func (e *Expression) AST() (Expr, error) {
+	return e.node, e.err
 }
 
 // Parse implements participle.Parseable for Expression.
@@ -197,6 +242,7 @@ func (e *Expression) Parse(lex *lexer.PeekingLexer) error {
 	}
 
 	e.Parts = parts
+	e.node, e.err = ParseLexemes(parts)
 	return nil
 }
 
@@ -12,7 +12,6 @@ import (
 )
 
 const (
-	blockSpacing       = 3.0
 	defaultTableRowGap = 0.0
 	cellPadding        = 1.2
 )
@@ -25,68 +24,109 @@ func Build(doc *dsl.Document, data any, opts BuildOptions) (*Result, error) {
 	if opts.Typesetter == nil {
 		return nil, fmt.Errorf("layout: 缺少排版后端 Typesetter")
 	}
+	if err := opts.Schema.Validate(data); err != nil {
+		return nil, err
+	}
 
 	res, err := collectResources(doc)
 	if err != nil {
 		return nil, err
 	}
-	meta := collectMeta(doc)
-	pageSection := firstPage(doc)
-	if pageSection == nil {
-		return nil, fmt.Errorf("文档中缺少 page 段落")
+	// 所有段落共享同一个根作用域，自定义函数与缺失绑定策略在此设置
+	scope := binding.NewScope(data).Funcs(opts.Funcs).Missing(opts.MissingBinding)
+	meta, err := collectMeta(doc, scope)
+	if err != nil {
+		return nil, err
 	}
-
-	pages, err := buildPages(pageSection, res, data, opts)
+	sets, err := collectPageSets(doc)
 	if err != nil {
 		return nil, err
 	}
 
+	// 按声明顺序排版每个 page 段落，各段落使用自身的尺寸、方向、边距与页眉页脚。
+	var pages []Page
+	for _, section := range doc.Sections {
+		if section.Page == nil {
+			continue
+		}
+		sectionPages, err := buildPages(section.Page, sets, res, scope, opts)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, sectionPages...)
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("文档中缺少 page 段落")
+	}
+	unresolved := scope.Unresolved()
+	if opts.MissingBinding == binding.MissingError && len(unresolved) > 0 {
+		return nil, &binding.UnresolvedError{Unresolved: unresolved}
+	}
+
 	return &Result{
-		Pages:     pages,
-		Resources: res,
-		Meta:      meta,
+		Pages:      pages,
+		Resources:  res,
+		Meta:       meta,
+		Unresolved: unresolved,
 	}, nil
 }
 
-func buildPages(section *dsl.PageSection, res ResourceSet, data any, opts BuildOptions) ([]Page, error) {
-	width, height, err := resolvePageSize(section.Spec)
+func buildPages(section *dsl.PageSection, sets map[string]*dsl.PageSetSection, res ResourceSet, scope *binding.Scope, opts BuildOptions) ([]Page, error) {
+	if section.Block == nil {
+		return nil, fmt.Errorf("page 段落缺少内容")
+	}
+	tpl, err := resolvePageTemplate(section, sets)
+	if err != nil {
+		return nil, err
+	}
+	// page-set 与页面内的 style 仅作用于当前段落
+	styles, err := scopedStyles(res.Styles, tpl.styles)
+	if err != nil {
+		return nil, err
+	}
+	res.Styles = styles
+
+	width, height, err := resolvePageSize(tpl.spec)
 	if err != nil {
 		return nil, err
 	}
 
-	margin := resolveMargin(section.Spec.Params)
+	margin := resolveMargin(tpl.spec.Params)
 	collector := newPageCollector(width, height, margin)
 
-	// 先扫描页眉/页脚定义，计算其高度与元素，更新内容区域。
-	if section.Block == nil {
-		return nil, fmt.Errorf("page 段落缺少内容")
-	}
-	var headerDef, footerDef *dsl.Command
-	for _, st := range section.Block.Statements {
-		if st.Command == nil {
-			continue
-		}
-		switch st.Command.Name {
-		case "header":
-			headerDef = st.Command
-		case "footer":
-			footerDef = st.Command
-		}
-	}
-	if headerDef != nil {
-		hf, err := buildHeaderFooter(headerDef, width, height, margin, res, data, opts.Typesetter, opts.Debug, "header")
+	// 先布局页眉/页脚，计算其高度与元素，更新内容区域。
+	if tpl.header != nil {
+		hf, err := buildHeaderFooter(tpl.header, width, height, margin, res, scope, opts.Typesetter, opts.Debug, "header")
 		if err != nil {
 			return nil, err
 		}
 		collector.header = hf
 	}
-	if footerDef != nil {
-		hf, err := buildHeaderFooter(footerDef, width, height, margin, res, data, opts.Typesetter, opts.Debug, "footer")
+	if tpl.footer != nil {
+		hf, err := buildHeaderFooter(tpl.footer, width, height, margin, res, scope, opts.Typesetter, opts.Debug, "footer")
 		if err != nil {
 			return nil, err
 		}
 		collector.footer = hf
 	}
+	// 模板中的背景形状在每一页重复绘制
+	for _, cmd := range tpl.background {
+		_, attrs := parseArgs(cmd.Args, false)
+		switch strings.ToLower(cmd.Name) {
+		case "line":
+			if ln, ok := parseLineShape(attrs, res); ok {
+				collector.background.lines = append(collector.background.lines, ln)
+			}
+		case "rect":
+			if rc, ok := parseRectShape(attrs, res); ok {
+				collector.background.rects = append(collector.background.rects, rc)
+			}
+		case "circle":
+			if c, ok := parseCircleShape(attrs, res); ok {
+				collector.background.circles = append(collector.background.circles, c)
+			}
+		}
+	}
 
 	// 根上下文从内容区域顶部开始排版。
 	root := &flowContext{
@@ -94,7 +134,7 @@ func buildPages(section *dsl.PageSection, res ResourceSet, data any, opts BuildO
 		baseY:          collector.contentTop(),
 		width:          width - margin.Left - margin.Right,
 		cursorY:        collector.contentTop(),
-		data:           data,
+		data:           scope,
 		typesetter:     opts.Typesetter,
 		debug:          opts.Debug,
 		parent:         nil,
@@ -111,60 +151,93 @@ func buildPages(section *dsl.PageSection, res ResourceSet, data any, opts BuildO
 	return collector.pages(), nil
 }
 
-// processBlock 会依次处理 block 内的命令，支持 flow、absolute、text、image、table。
+// processBlock 会依次处理 block 内的命令，支持 flow、absolute、text、image、table、grid、row、columns、pagebreak，
+// 以及 let/if/elif/else/for 控制语句（由 walkStatements 展开，子语句在对应作用域中布局）。
+// 块级命令上的 break-before/break-after/keep-together/keep-with-next 由 layoutKept 处理。
 func processBlock(block *dsl.Block, ctx *flowContext, res ResourceSet) error {
-	for _, stmt := range block.Statements {
+	var pending *keepGroup
+	err := walkStatements(block, ctx.data, func(stmt *dsl.Statement, data any) error {
 		if stmt.Command == nil {
-			continue
+			return nil
 		}
 		cmd := stmt.Command
-		switch cmd.Name {
-		case "flow":
-			if err := handleFlow(cmd, ctx, res); err != nil {
-				return err
-			}
-		case "absolute":
-			if err := handleAbsolute(cmd, ctx, res); err != nil {
-				return err
-			}
-		case "text":
-			if err := handleText(cmd, ctx, res); err != nil {
-				return err
-			}
-		case "image":
-			if err := handleImage(cmd, ctx, res); err != nil {
-				return err
-			}
-		case "table":
-			if err := handleTable(cmd, ctx, res); err != nil {
-				return err
-			}
-		default:
-			// 形状命令（page-level 背景图形，坐标为页面坐标，允许在任意层级声明）
-			name := strings.ToLower(cmd.Name)
-			if name == "line" || name == "rect" || name == "circle" {
-				_, attrs := parseArgs(cmd.Args, false)
-				switch name {
-				case "line":
-					if ln, ok := parseLineShape(attrs, res); ok {
-						ctx.collector.curr().lines = append(ctx.collector.curr().lines, ln)
-					}
-				case "rect":
-					if rc, ok := parseRectShape(attrs, res); ok {
-						ctx.collector.curr().rects = append(ctx.collector.curr().rects, rc)
-					}
-				case "circle":
-					if c, ok := parseCircleShape(attrs, res); ok {
-						ctx.collector.curr().circles = append(ctx.collector.curr().circles, c)
-					}
+		layoutCmd := func() error {
+			saved := ctx.data
+			ctx.data = data
+			defer func() { ctx.data = saved }()
+			return layoutCommand(cmd, ctx, res)
+		}
+		if cmd.Name == "pagebreak" {
+			ctx.explicitBreak()
+			pending = nil
+			return nil
+		}
+		attrs := blockAttrs(cmd, res)
+		if attrs == nil {
+			return layoutCmd()
+		}
+		return layoutKept(ctx, attrs, layoutCmd, &pending)
+	})
+	if err != nil {
+		return err
+	}
+	// 块结束时越过尚未结束的浮动区域，使容器高度包含浮动图片
+	ctx.clearFloats()
+	return nil
+}
+
+// layoutCommand 排版单个命令。
+func layoutCommand(cmd *dsl.Command, ctx *flowContext, res ResourceSet) error {
+	switch cmd.Name {
+	case "flow", "table", "grid", "row", "columns":
+		// 块级容器不参与绕排，从浮动区域下方开始
+		ctx.clearFloats()
+	}
+	switch cmd.Name {
+	case "flow":
+		return handleFlow(cmd, ctx, res)
+	case "absolute":
+		return handleAbsolute(cmd, ctx, res)
+	case "text":
+		return handleText(cmd, ctx, res)
+	case "image":
+		return handleImage(cmd, ctx, res)
+	case "table":
+		return handleTable(cmd, ctx, res)
+	case "grid":
+		return handleGrid(cmd, ctx, res)
+	case "row":
+		return handleRow(cmd, ctx, res)
+	case "columns":
+		return handleColumns(cmd, ctx, res)
+	default:
+		// 形状命令（page-level 背景图形，坐标为页面坐标，允许在任意层级声明；单元格内相对于单元格内容区域）
+		name := strings.ToLower(cmd.Name)
+		if name == "line" || name == "rect" || name == "circle" {
+			_, attrs := parseArgs(cmd.Args, false)
+			pc := ctx.collector
+			switch name {
+			case "line":
+				if ln, ok := parseLineShape(attrs, res); ok {
+					ln.X1, ln.Y1, ln.X2, ln.Y2 = ln.X1+pc.originX, ln.Y1+pc.originY, ln.X2+pc.originX, ln.Y2+pc.originY
+					pc.curr().lines = append(pc.curr().lines, ln)
+				}
+			case "rect":
+				if rc, ok := parseRectShape(attrs, res); ok {
+					rc.X, rc.Y = rc.X+pc.originX, rc.Y+pc.originY
+					pc.curr().rects = append(pc.curr().rects, rc)
+				}
+			case "circle":
+				if c, ok := parseCircleShape(attrs, res); ok {
+					c.CX, c.CY = c.CX+pc.originX, c.CY+pc.originY
+					pc.curr().circles = append(pc.curr().circles, c)
 				}
-				continue
 			}
-			// 其余命令暂未实现，忽略即可
-			continue
+			return nil
 		}
+		// 其余命令暂未实现，忽略即可
+		return nil
 	}
-	return nil
 }
 
 func normalizeWrap(v string) string {
@@ -189,6 +262,8 @@ func handleFlow(cmd *dsl.Command, parent *flowContext, res ResourceSet) error {
 	}
 	styleName, attrs := parseArgs(cmd.Args, false)
 	attrs = mergeStyleAttributes(styleName, attrs, res.Styles)
+	box := parseFlowBox(attrs, res)
+	insets := box.insets()
 	width := parent.width
 	if v := attrs["width"]; v != "" {
 		if w := parseDimension(v, parent.width); w > 0 && w <= parent.width {
@@ -196,11 +271,12 @@ func handleFlow(cmd *dsl.Command, parent *flowContext, res ResourceSet) error {
 		}
 	} else if a := strings.ToLower(attrs["align"]); a == "center" || a == "right" || a == "end" {
 		if inferred := inferFlowWidth(cmd.Block, res, parent.width, parent.typesetter, parent.data); inferred > 0 {
-			width = math.Min(inferred, parent.width)
+			width = math.Min(inferred+insets.Left+insets.Right, parent.width)
 		}
 	}
 
 	offset := alignOffset(parent.width, width, attrs["align"])
+	before, after := blockMargins(attrs, res)
 
 	// 规范化本 flow 的文本对齐方式，供子 text 继承
 	flowAlign := strings.ToLower(attrs["align"])
@@ -219,6 +295,10 @@ func handleFlow(cmd *dsl.Command, parent *flowContext, res ResourceSet) error {
 		flowWrap = normalizeWrap(v)
 	}
 
+	if !box.empty() {
+		return layoutFlowBox(cmd, parent, res, box, parent.baseX+offset, width, before, after, flowAlign, flowWrap)
+	}
+
 	child := &flowContext{
 		baseX:          parent.baseX + offset,
 		baseY:          parent.cursorY,
@@ -233,15 +313,63 @@ func handleFlow(cmd *dsl.Command, parent *flowContext, res ResourceSet) error {
 		allowPageBreak: parent.allowPageBreak,
 		textAlign:      flowAlign,
 		textWrap:       flowWrap,
+		// flow 自身的上边距与首个子块的上边距折叠
+		spaceAfter: math.Max(parent.spaceAfter, before),
 	}
+	parent.spaceAfter = 0
 
 	if err := processBlock(cmd.Block, child, res); err != nil {
 		return err
 	}
 
 	if child.cursorY > parent.cursorY {
-		parent.cursorY = child.cursorY + blockSpacing
+		// 末个子块的下边距与 flow 自身的下边距折叠
+		parent.cursorY = child.cursorY
+		parent.endBlock(math.Max(child.spaceAfter, after))
+	} else {
+		// 没有放置任何内容的 flow（如空循环）不占位，其上下边距与前一块的下边距一起折叠
+		parent.spaceAfter = math.Max(child.spaceAfter, after)
+	}
+	return nil
+}
+
+// layoutFlowBox 排版带外观的 flow：内容区域按内边距与边框内缩，外边距不再与子块折叠，
+// 排版完成后按最终高度生成背景矩形。
+func layoutFlowBox(cmd *dsl.Command, parent *flowContext, res ResourceSet, box flowBox, x, width, before, after float64, textAlign, textWrap string) error {
+	insets := box.insets()
+	parent.beginBlock(before)
+	// 至少要放得下上下内边距，否则从下一页开始
+	parent.ensureSpace(insets.Top + insets.Bottom)
+	top := parent.cursorY
+
+	child := &flowContext{
+		baseX:          x + insets.Left,
+		baseY:          top + insets.Top,
+		width:          math.Max(width-insets.Left-insets.Right, 0),
+		cursorY:        top + insets.Top,
+		data:           parent.data,
+		typesetter:     parent.typesetter,
+		debug:          parent.debug,
+		parent:         parent,
+		collector:      parent.collector,
+		margin:         parent.margin,
+		allowPageBreak: parent.allowPageBreak,
+		textAlign:      textAlign,
+		textWrap:       textWrap,
+	}
+	if parent.collector != nil {
+		child.box = &boxTracker{inset: insets.Left, open: segmentAt(parent, x, top)}
 	}
+	if err := processBlock(cmd.Block, child, res); err != nil {
+		return err
+	}
+
+	bottom := math.Max(child.cursorY+insets.Bottom, top+box.minHeight)
+	if child.box != nil {
+		child.box.emit(parent.collector, box, width, bottom)
+	}
+	parent.cursorY = bottom
+	parent.endBlock(after)
 	return nil
 }
 
@@ -288,27 +416,46 @@ func handleText(cmd *dsl.Command, ctx *flowContext, res ResourceSet) error {
 			attrs["align"] = ctx.textAlign
 		}
 	}
-	content := extractText(cmd.Block)
-	if content == "" {
+	if extractText(cmd.Block) == "" {
 		return fmt.Errorf("text 语句缺少文本内容")
 	}
+	content, err := expandText(cmd.Block, ctx.data)
+	if err != nil {
+		return err
+	}
 
 	// 计算折行策略：text 覆盖 flow，默认 anywhere
 	effWrap := ctx.textWrap
 	if v, ok := attrs["wrap"]; ok && strings.TrimSpace(v) != "" {
 		effWrap = normalizeWrap(v)
 	}
-	tb, height, err := composeTextBox(styleName, attrs, content, ctx.baseX, ctx.cursorY, ctx.width, res, ctx.data, ctx.typesetter, ctx.debug, effWrap)
+	before, after := blockMargins(attrs, res)
+	ctx.beginBlock(before)
+	tb, _, err := composeTextBox(styleName, attrs, content, ctx.baseX, ctx.cursorY, ctx.width, res, ctx.typesetter, ctx.debug, effWrap)
 	if err != nil {
 		return err
 	}
-	ctx.ensureSpace(height)
-	tb.X = ctx.baseX
-	tb.Y = ctx.cursorY
-	if acc := ctx.acc(); acc != nil {
-		acc.appendText(tb)
+	// 与浮动图片重叠的行按让出后的宽度重新排版
+	var reflow func(n int) (TextBox, error)
+	if indents, ok := ctx.floatIndents(tb); !ok {
+		ctx.clearFloats()
+	} else if len(indents) > 0 {
+		compose := func(indents []lineIndent) (TextBox, error) {
+			tb, _, err := composeText(styleName, attrs, content, ctx.baseX, ctx.cursorY, ctx.width, indents, res, ctx.typesetter, ctx.debug, effWrap)
+			return tb, err
+		}
+		if tb, err = compose(indents); err != nil {
+			return err
+		}
+		// 换页后浮动区域不再存在：只保留前 n 行的让出宽度，其余行按整宽排版
+		reflow = func(n int) (TextBox, error) {
+			return compose(indents[:minInt(n, len(indents))])
+		}
+	}
+	if err := placeTextBox(ctx, tb, attrs, reflow); err != nil {
+		return err
 	}
-	ctx.cursorY += height + blockSpacing
+	ctx.endBlock(after)
 	return nil
 }
 
@@ -380,170 +527,32 @@ func handleImage(cmd *dsl.Command, ctx *flowContext, res ResourceSet) error {
 		return fmt.Errorf("image 语句缺少资源或 src")
 	}
 
-	ctx.ensureSpace(imgBox.Height)
-	imgBox.Y = ctx.cursorY
-	if acc := ctx.acc(); acc != nil {
-		acc.appendImage(imgBox)
-	}
-	ctx.cursorY = imgBox.Y + imgBox.Height + blockSpacing
-	return nil
-}
-
-func handleTable(cmd *dsl.Command, ctx *flowContext, res ResourceSet) error {
-	if cmd.Block == nil {
-		return fmt.Errorf("table 语句缺少内容")
-	}
-	styleName, attrs := parseArgs(cmd.Args, false)
-	attrs = mergeStyleAttributes(styleName, attrs, res.Styles)
-
-	width := ctx.width
-	if v := attrs["width"]; v != "" {
-		if w := parseDimension(v, ctx.width); w > 0 {
-			width = w
-		}
-	}
-	rowGap := defaultTableRowGap
-	if v := attrs["row-gap"]; v != "" {
-		if g := parseLength(v); g >= 0 {
-			rowGap = g
-		}
-	} else if v := attrs["rowGap"]; v != "" {
-		if g := parseLength(v); g >= 0 {
-			rowGap = g
-		}
-	}
-	columns := 0
-	if v := attrs["columns"]; v != "" {
-		if c, err := strconv.Atoi(v); err == nil && c > 0 {
-			columns = c
-		}
-	}
-
-	build := func(baseY float64) (TableBox, float64, error) {
-		table := TableBox{
-			X:           ctx.baseX,
-			Y:           baseY,
-			Width:       width,
-			RowGap:      rowGap,
-			BorderColor: Color{R: 200, G: 200, B: 200},
-		}
-		currentY := baseY
-		colCount := columns
-		for _, stmt := range cmd.Block.Statements {
-			if stmt.Command == nil {
-				continue
-			}
-			switch stmt.Command.Name {
-			case "header":
-				row, rowHeight, rowColumns, err := buildTableRow(stmt.Command, res, colCount, width, table.X, currentY, true, ctx.data, ctx.typesetter, ctx.debug)
-				if err != nil {
-					return TableBox{}, 0, err
-				}
-				if colCount == 0 {
-					colCount = rowColumns
-				}
-				currentY += rowHeight + table.RowGap
-				row.Y = currentY - rowHeight - table.RowGap
-				table.Rows = append(table.Rows, row)
-			case "row":
-				row, rowHeight, _, err := buildTableRow(stmt.Command, res, colCount, width, table.X, currentY, false, ctx.data, ctx.typesetter, ctx.debug)
-				if err != nil {
-					return TableBox{}, 0, err
-				}
-				currentY += rowHeight + table.RowGap
-				row.Y = currentY - rowHeight - table.RowGap
-				table.Rows = append(table.Rows, row)
-			}
-		}
-		if colCount == 0 {
-			return TableBox{}, 0, fmt.Errorf("table 需要至少一个单元格")
-		}
-		colWidth := width / float64(colCount)
-		table.ColumnWidths = make([]float64, colCount)
-		for i := 0; i < colCount; i++ {
-			table.ColumnWidths[i] = colWidth
-		}
-		if len(table.Rows) > 0 {
-			currentY -= table.RowGap
-		}
-		return table, currentY - baseY, nil
-	}
-
-	table, height, err := build(ctx.cursorY)
-	if err != nil {
-		return err
-	}
-	if ctx.allowPageBreak && ctx.cursorY+height > ctx.collector.maxContentY() {
-		ctx.pageBreak()
-		table, height, err = build(ctx.cursorY)
-		if err != nil {
-			return err
+	switch side := strings.ToLower(strings.TrimSpace(attrs["float"])); side {
+	case "left", "right":
+		gap := defaultFloatGap
+		if v := attrs["gap"]; v != "" {
+			gap = math.Max(parseLength(v), 0)
 		}
+		placeFloat(ctx, imgBox, side == "right", gap)
+		return nil
+	case "", "none":
+	default:
+		return fmt.Errorf("image float 取值无效：%s（可选 left、right、none）", attrs["float"])
 	}
+	ctx.clearFloats()
 
+	before, after := blockMargins(attrs, res)
+	ctx.beginBlock(before)
+	ctx.ensureSpace(imgBox.Height)
+	imgBox.X, imgBox.Y = ctx.baseX, ctx.cursorY
 	if acc := ctx.acc(); acc != nil {
-		acc.appendTable(table)
+		acc.appendImage(imgBox)
 	}
-	ctx.cursorY += height + blockSpacing
+	ctx.cursorY = imgBox.Y + imgBox.Height
+	ctx.endBlock(after)
 	return nil
 }
 
-func buildTableRow(cmd *dsl.Command, res ResourceSet, columnHint int, tableWidth, baseX, baseY float64, header bool, data any, ts Typesetter, debug DebugOptions) (TableRow, float64, int, error) {
-	var row TableRow
-	if cmd.Block == nil {
-		return row, 0, 0, fmt.Errorf("row/header 缺少 cell 定义")
-	}
-	row.IsHeader = header
-	colIdx := 0
-	maxHeight := 0.0
-	cells := []TableCell{}
-
-	for _, stmt := range cmd.Block.Statements {
-		if stmt.Command == nil || stmt.Command.Name != "cell" {
-			continue
-		}
-		styleName, attrs := parseArgs(stmt.Command.Args, true)
-		attrs = mergeStyleAttributes(styleName, attrs, res.Styles)
-		content := extractText(stmt.Command.Block)
-		if content == "" {
-			continue
-		}
-
-		columns := columnHint
-		if columns == 0 {
-			columns = 1
-		}
-		colWidth := tableWidth / float64(columns)
-		x := baseX + float64(colIdx)*colWidth
-		cellWidth := colWidth - 2*cellPadding
-		if cellWidth <= 0 {
-			cellWidth = colWidth
-		}
-		// 单元格折行策略：默认继承表/flow 含义不易获取，这里按属性值或默认 anywhere
-		wrap := normalizeWrap(attrs["wrap"])
-		if wrap == "" {
-			wrap = "anywhere"
-		}
-		tb, height, err := composeTextBox(styleName, attrs, content, x+cellPadding, baseY+cellPadding, cellWidth, res, data, ts, debug, wrap)
-		if err != nil {
-			return row, 0, columnHint, err
-		}
-		cells = append(cells, TableCell{Text: tb})
-		if height > maxHeight {
-			maxHeight = height
-		}
-		colIdx++
-	}
-
-	if colIdx == 0 {
-		return row, 0, columnHint, fmt.Errorf("row/header 中至少需要一个 cell")
-	}
-
-	row.Cells = cells
-	row.Height = maxHeight + 2*cellPadding
-	return row, row.Height, colIdx, nil
-}
-
 type pageAccumulator struct {
 	texts   []TextBox
 	images  []ImageBox
@@ -574,6 +583,10 @@ type pageCollector struct {
 	// 页眉/页脚布局结果，应用于所有页面
 	header HeaderFooter
 	footer HeaderFooter
+	// background 保存 page-set 模板中的背景形状，绘制在每一页的主体形状之前
+	background pageAccumulator
+	// originX/originY 为形状命令坐标的原点：页面上为 0，单元格中为单元格内容区域的左上角
+	originX, originY float64
 }
 
 func newPageCollector(width, height float64, margin Margin) *pageCollector {
@@ -632,9 +645,9 @@ func (pc *pageCollector) allPages() []Page {
 			Texts:   acc.texts,
 			Images:  acc.images,
 			Tables:  acc.tables,
-			Lines:   acc.lines,
-			Rects:   acc.rects,
-			Circles: acc.circles,
+			Lines:   concatShapes(pc.background.lines, acc.lines),
+			Rects:   concatShapes(pc.background.rects, acc.rects),
+			Circles: concatShapes(pc.background.circles, acc.circles),
 			Header:  pc.header,
 			Footer:  pc.footer,
 		}
@@ -642,6 +655,16 @@ func (pc *pageCollector) allPages() []Page {
 	return out
 }
 
+// concatShapes 将模板背景形状与页面自身形状拼接为新切片，避免多页共享底层数组。
+func concatShapes[T any](background, own []T) []T {
+	if len(background) == 0 {
+		return own
+	}
+	out := make([]T, 0, len(background)+len(own))
+	out = append(out, background...)
+	return append(out, own...)
+}
+
 func (pc *pageCollector) pages() []Page {
 	return pc.allPages()
 }
@@ -662,6 +685,16 @@ type flowContext struct {
 	textAlign string
 	// textWrap 继承自父 flow 的折行方式（anywhere(默认)/break-word/nowrap）。
 	textWrap string
+	// spaceAfter 为上一块尚未计入 cursorY 的下边距，与下一块的上边距折叠。
+	spaceAfter float64
+	// blockStart、blockTop 为最近一次 beginBlock 推进光标之前、之后的位置。
+	blockStart, blockTop float64
+	// columns 不为空时本上下文是分栏容器，换页前先换栏。
+	columns *columnState
+	// box 不为空时本上下文是带外观的 flow，记录背景在各页各栏中的分段。
+	box *boxTracker
+	// floats 为当前页（栏）上尚未越过的浮动图片区域，之后的 text 绕其排版。
+	floats []floatArea
 }
 
 // buildHeaderFooter 负责解析与布局页眉/页脚内容（仅支持 text/image）。
@@ -681,24 +714,28 @@ func buildHeaderFooter(cmd *dsl.Command, pageW, pageH float64, margin Margin, re
 	var rects []Rect
 	var circles []Circle
 	cursorY := 0.0
+	spaceAfter := 0.0 // 上一项的下边距，与下一项的上边距折叠
 
 	// 布局内部的 text/image/shape，按顺序自上而下堆叠（shape 不参与 header 内容高度计算）
-	for _, st := range cmd.Block.Statements {
+	err := walkStatements(cmd.Block, data, func(st *dsl.Statement, data any) error {
 		if st.Command == nil {
-			continue
+			return nil
 		}
 		switch st.Command.Name {
 		case "text":
 			styleName, tattrs := parseArgs(st.Command.Args, true)
 			all := mergeStyleAttributes(styleName, tattrs, res.Styles)
-			content := extractText(st.Command.Block)
+			content, err := expandText(st.Command.Block, data)
+			if err != nil {
+				return err
+			}
 			wrap := normalizeWrap(all["wrap"])
 			if wrap == "" {
 				wrap = "anywhere"
 			}
-			tb, h, err := composeTextBox(styleName, all, content, margin.Left, 0, contentWidth, res, data, ts, debug, wrap)
+			tb, h, err := composeTextBox(styleName, all, content, margin.Left, 0, contentWidth, res, ts, debug, wrap)
 			if err != nil {
-				return hf, err
+				return err
 			}
 			// 页眉文本水平对齐（默认 center，可被子元素 align 属性覆盖：left/center/right）
 			if kind == "header" {
@@ -716,9 +753,12 @@ func buildHeaderFooter(cmd *dsl.Command, pageW, pageH float64, margin Margin, re
 				}
 				tb.Align = align
 			}
+			before, after := blockMargins(all, res)
+			cursorY += math.Max(spaceAfter, before)
 			tb.Y = cursorY + tb.Y // composeTextBox 的 Y 为 0，这里使用累积偏移
 			texts = append(texts, tb)
-			cursorY += h + blockSpacing
+			cursorY += h
+			spaceAfter = after
 		case "image":
 			styleName, iattrs := parseArgs(st.Command.Args, true)
 			iattrs = mergeStyleAttributes(styleName, iattrs, res.Styles)
@@ -732,6 +772,8 @@ func buildHeaderFooter(cmd *dsl.Command, pageW, pageH float64, margin Margin, re
 			if imageName == "" && len(st.Command.Args) > 0 {
 				imageName = st.Command.Args[0].Value
 			}
+			before, after := blockMargins(iattrs, res)
+			cursorY += math.Max(spaceAfter, before)
 			img := ImageBox{X: margin.Left, Y: cursorY, Fit: iattrs["fit"], Opacity: 1}
 			if v := iattrs["opacity"]; v != "" {
 				if f, err := strconv.ParseFloat(v, 64); err == nil {
@@ -785,7 +827,8 @@ func buildHeaderFooter(cmd *dsl.Command, pageW, pageH float64, margin Margin, re
 				}
 			}
 			images = append(images, img)
-			cursorY += img.Height + blockSpacing
+			cursorY += img.Height
+			spaceAfter = after
 		case "line", "rect", "circle":
 			_, a := parseArgs(st.Command.Args, false)
 			name := strings.ToLower(st.Command.Name)
@@ -805,9 +848,10 @@ func buildHeaderFooter(cmd *dsl.Command, pageW, pageH float64, margin Margin, re
 			}
 			// 形状不改变 header 内 content cursor
 		}
-	}
-	if cursorY > 0 {
-		cursorY -= blockSpacing // 去掉最后一项后的额外间距
+		return nil
+	})
+	if err != nil {
+		return hf, err
 	}
 
 	// contentHeight 表示页眉/页脚内部内容自身高度（不包含额外区域）
@@ -856,20 +900,37 @@ func (ctx *flowContext) ensureSpace(height float64) {
 	if ctx.collector == nil {
 		return
 	}
-	if ctx.cursorY+height <= ctx.collector.maxContentY() {
+	if ctx.cursorY+height <= ctx.contentBottom() {
 		return
 	}
 	ctx.pageBreak()
 }
 
+// pageBreak 换到下一页；位于分栏中时先换到下一栏。祖先上下文随之换页，子上下文跟随父上下文的新位置。
 func (ctx *flowContext) pageBreak() {
 	if ctx.collector == nil {
 		return
 	}
+	ctx.floats = nil
+	if ctx.box != nil {
+		ctx.box.close(ctx)
+		defer ctx.box.reopen(ctx)
+	}
+	if ctx.columns != nil && ctx.columns.next(ctx) {
+		return
+	}
 	if ctx.parent != nil {
+		px := ctx.parent.baseX
 		ctx.parent.pageBreak()
+		dx := ctx.parent.baseX - px
+		ctx.baseX += dx
 		ctx.baseY = ctx.parent.cursorY
 		ctx.cursorY = ctx.baseY
+		ctx.spaceAfter = 0
+		if c := ctx.columns; c != nil {
+			c.left += dx
+			c.reset(ctx)
+		}
 		return
 	}
 	ctx.collector.newPage()
@@ -877,6 +938,7 @@ func (ctx *flowContext) pageBreak() {
 	// 新页从内容区域顶部开始（考虑页眉高度）
 	ctx.baseY = ctx.collector.contentTop()
 	ctx.cursorY = ctx.baseY
+	ctx.spaceAfter = 0
 }
 
 func (ctx *flowContext) acc() *pageAccumulator {
@@ -888,10 +950,11 @@ func (ctx *flowContext) acc() *pageAccumulator {
 
 func collectResources(doc *dsl.Document) (ResourceSet, error) {
 	res := ResourceSet{
-		Fonts:  map[string]FontResource{},
-		Colors: map[string]Color{},
-		Images: map[string]ImageResource{},
-		Styles: map[string]Style{},
+		Fonts:        map[string]FontResource{},
+		Colors:       map[string]Color{},
+		Images:       map[string]ImageResource{},
+		Styles:       map[string]Style{},
+		BlockSpacing: defaultBlockSpacing,
 	}
 	rawStyles := map[string]Style{}
 
@@ -900,6 +963,15 @@ func collectResources(doc *dsl.Document) (ResourceSet, error) {
 			continue
 		}
 		for _, stmt := range section.Resources.Block.Statements {
+			if a := stmt.Assignment; a != nil {
+				// 文档级设置：block-spacing 为块与块之间的默认间距
+				if strings.EqualFold(a.Key, "block-spacing") {
+					if v := valueToString(a.Value); v != "" {
+						res.BlockSpacing = math.Max(parseLength(v), 0)
+					}
+				}
+				continue
+			}
 			if stmt.Command == nil {
 				continue
 			}
@@ -949,7 +1021,8 @@ func collectResources(doc *dsl.Document) (ResourceSet, error) {
 	return res, nil
 }
 
-func collectMeta(doc *dsl.Document) DocumentMeta {
+// collectMeta 收集 meta 段落中的文档信息，字符串中的 ${...} 按 data 插值。
+func collectMeta(doc *dsl.Document, data any) (DocumentMeta, error) {
 	meta := DocumentMeta{
 		Creator: "Papyrus",
 	}
@@ -963,20 +1036,52 @@ func collectMeta(doc *dsl.Document) DocumentMeta {
 			}
 			key := strings.ToLower(stmt.Assignment.Key)
 			switch key {
-			case "title":
-				meta.Title = valueToString(stmt.Assignment.Value)
-			case "author":
-				meta.Author = valueToString(stmt.Assignment.Value)
-			case "subject":
-				meta.Subject = valueToString(stmt.Assignment.Value)
-			case "creator":
-				meta.Creator = valueToString(stmt.Assignment.Value)
+			case "title", "author", "subject", "creator":
+				text, err := expandValue(stmt.Assignment.Value, data)
+				if err != nil {
+					return meta, err
+				}
+				switch key {
+				case "title":
+					meta.Title = text
+				case "author":
+					meta.Author = text
+				case "subject":
+					meta.Subject = text
+				default:
+					meta.Creator = text
+				}
 			case "keywords":
-				meta.Keywords = valueToStringSlice(stmt.Assignment.Value)
+				values := []*dsl.Value{stmt.Assignment.Value}
+				if arr := stmt.Assignment.Value.Array; arr != nil {
+					values = arr.Values
+				}
+				for _, v := range values {
+					text, err := expandValue(v, data)
+					if err != nil {
+						return meta, err
+					}
+					if text != "" {
+						meta.Keywords = append(meta.Keywords, text)
+					}
+				}
 			}
 		}
 	}
-	return meta
+	return meta, nil
+}
+
+// expandValue 将赋值转换为字符串并展开其中的 ${...} 插值。
+func expandValue(val *dsl.Value, data any) (string, error) {
+	text := valueToString(val)
+	if val == nil || val.String == nil {
+		return text, nil
+	}
+	// 字符串内容从左引号之后开始
+	pos := val.Pos
+	pos.Column++
+	pos.Offset++
+	return binding.Expand(text, pos, data)
 }
 
 func parseFontResource(cmd *dsl.Command) FontResource {
@@ -1081,11 +1186,7 @@ func parseStyleResource(cmd *dsl.Command) Style {
 		if stmt.Assignment == nil {
 			continue
 		}
-		val := valueToString(stmt.Assignment.Value)
-		if val == "" {
-			continue
-		}
-		style.Props[stmt.Assignment.Key] = val
+		flattenValue(stmt.Assignment.Key, stmt.Assignment.Value, style.Props)
 	}
 	return style
 }
@@ -1155,12 +1256,19 @@ func resolvePageSize(spec dsl.PageSpec) (float64, float64, error) {
 
 	width := base[0]
 	height := base[1]
+	// 以最后一次出现的方向为准，便于页面覆盖 page-set 中声明的方向
+	landscape := false
 	for _, token := range spec.Params {
 		switch token.Value {
 		case "landscape":
-			width, height = height, width
+			landscape = true
+		case "portrait":
+			landscape = false
 		}
 	}
+	if landscape {
+		width, height = height, width
+	}
 	return width, height, nil
 }
 
@@ -1212,15 +1320,6 @@ func resolveMargin(params []*dsl.Lexeme) Margin {
 	return margin
 }
 
-func firstPage(doc *dsl.Document) *dsl.PageSection {
-	for _, section := range doc.Sections {
-		if section.Page != nil {
-			return section.Page
-		}
-	}
-	return nil
-}
-
 func parseArgs(args []*dsl.Lexeme, allowStyle bool) (string, map[string]string) {
 	result := map[string]string{}
 	if len(args) == 0 {
@@ -1272,7 +1371,36 @@ func extractText(block *dsl.Block) string {
 	return builder.String()
 }
 
-func composeTextBox(style string, attrs map[string]string, content string, x, y, width float64, res ResourceSet, data any, ts Typesetter, debug DebugOptions, wrap string) (TextBox, float64, error) {
+// expandText 拼接 block 中的文本字面量并展开其中的 ${...} 插值，表达式错误会带上源码位置。
+// 插值结果经 escapeInline 转义，行内标记只从模板源码中解析；raw() 的结果不转义。
+func expandText(block *dsl.Block, data any) (string, error) {
+	if block == nil {
+		return "", nil
+	}
+	var builder strings.Builder
+	for _, stmt := range block.Statements {
+		if stmt.Text == nil {
+			continue
+		}
+		// 文本内容从左引号之后开始
+		pos := stmt.Text.Pos
+		pos.Column++
+		pos.Offset++
+		text, err := binding.ExpandMarkup(string(stmt.Text.Value), pos, data, escapeInline)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(text)
+	}
+	return builder.String(), nil
+}
+
+func composeTextBox(style string, attrs map[string]string, content string, x, y, width float64, res ResourceSet, ts Typesetter, debug DebugOptions, wrap string) (TextBox, float64, error) {
+	return composeText(style, attrs, content, x, y, width, nil, res, ts, debug, wrap)
+}
+
+// composeText 与 composeTextBox 相同，indents 为前若干行因浮动元素让出的宽度（见 float.go）。
+func composeText(style string, attrs map[string]string, content string, x, y, width float64, indents []lineIndent, res ResourceSet, ts Typesetter, debug DebugOptions, wrap string) (TextBox, float64, error) {
 	// 说明：为支持 Typst 风格的行内下划线，如 #underline[文本]，这里在排版前先对内容做一次预处理，
 	// 将指令展开为纯文本，并记录需要下划线的区间，后续在换行后映射到每一行并由渲染器绘制。
 	attrs = mergeStyleAttributes(style, attrs, res.Styles)
@@ -1284,11 +1412,7 @@ func composeTextBox(style string, attrs map[string]string, content string, x, y,
 		fontName = "Body"
 	}
 
-	if data != nil {
-		content = binding.Interpolate(content, data)
-	}
-
-	// 预处理行内指令（当前支持 #underline[...] 与 \\# 转义）
+	// 预处理行内指令（当前支持 #underline[...] 与 \# \\ \[ \] 转义）
 	plainContent, underlineSpans := parseInlineTypst(content)
 
 	fontSize := parseLength(attrs["size"]) // mm
@@ -1313,10 +1437,21 @@ func composeTextBox(style string, attrs map[string]string, content string, x, y,
 		return TextBox{}, 0, err
 	}
 
-	lines, err := layoutLines(plainContent, width, fontRes, fontSize, lineHeight, ts, wrap)
+	widths := []float64{width}
+	if len(indents) > 0 {
+		widths = make([]float64, 0, len(indents)+1)
+		for _, ind := range indents {
+			widths = append(widths, width-ind.left-ind.right)
+		}
+		widths = append(widths, width)
+	}
+	lines, err := layoutLines(plainContent, widths, fontRes, fontSize, lineHeight, ts, wrap)
 	if err != nil {
 		return TextBox{}, 0, err
 	}
+	for i := 0; i < len(lines) && i < len(indents); i++ {
+		lines[i].Indent, lines[i].RightIndent = indents[i].left, indents[i].right
+	}
 
 	totalHeight := 0.0
 	defaultLeading := math.Max(lineHeight-fontSize, 0)
@@ -1419,7 +1554,15 @@ func composeTextBox(style string, attrs map[string]string, content string, x, y,
 	return tb, totalHeight, nil
 }
 
-// parseInlineTypst 解析简化的 Typst 风格行内指令，目前仅支持 #underline[...] 与 \\# 转义。
+// inlineEscaper 转义会被 parseInlineTypst 识别的字符。
+var inlineEscaper = strings.NewReplacer(`\`, `\\`, "#", `\#`, "[", `\[`, "]", `\]`)
+
+// escapeInline 使插值数据在 parseInlineTypst 中按字面输出，例如数据中的 #underline[x] 不会变成下划线。
+func escapeInline(s string) string {
+	return inlineEscaper.Replace(s)
+}
+
+// parseInlineTypst 解析简化的 Typst 风格行内指令，目前仅支持 #underline[...]，以及 \#、\\、\[、\] 四种转义（输出反斜杠后的字符）。
 // 返回展开后的纯文本，以及针对纯文本的下划线区间（按 rune 计数）。
 func parseInlineTypst(input string) (string, []TextSpan) {
 	var spans []TextSpan
@@ -1428,10 +1571,10 @@ func parseInlineTypst(input string) (string, []TextSpan) {
 	i := 0
 	for i < len(runes) {
 		r := runes[i]
-		// 处理转义：\# -> '#'
+		// 处理转义：\# \\ \[ \] 输出对应的字符
 		if r == '\\' {
-			if i+1 < len(runes) && runes[i+1] == '#' {
-				out.WriteRune('#')
+			if i+1 < len(runes) && strings.ContainsRune(`#\[]`, runes[i+1]) {
+				out.WriteRune(runes[i+1])
 				i += 2
 				continue
 			}
@@ -1495,8 +1638,18 @@ func parseInlineTypst(input string) (string, []TextSpan) {
 	return out.String(), spans
 }
 
-func maxInt(a, b int) int { if a > b { return a }; return b }
-func minInt(a, b int) int { if a < b { return a }; return a }
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
 
 func resolveFontResource(name string, res ResourceSet) (FontResource, error) {
 	if font, ok := res.Fonts[name]; ok {
@@ -1511,7 +1664,7 @@ func resolveFontResource(name string, res ResourceSet) (FontResource, error) {
 	return FontResource{}, fmt.Errorf("字体 %s 未定义，且没有可用的默认字体", name)
 }
 
-func layoutLines(content string, width float64, font FontResource, fontSize, lineHeight float64, ts Typesetter, wrap string) ([]TextLine, error) {
+func layoutLines(content string, widths []float64, font FontResource, fontSize, lineHeight float64, ts Typesetter, wrap string) ([]TextLine, error) {
 	if ts == nil {
 		lines := strings.Split(content, "\n")
 		out := make([]TextLine, 0, len(lines))
@@ -1520,22 +1673,22 @@ func layoutLines(content string, width float64, font FontResource, fontSize, lin
 			textHeight = 12
 		}
 		leading := math.Max(lineHeight-textHeight, 0)
-		for _, l := range lines {
+		for i, l := range lines {
 			out = append(out, TextLine{
 				Content:   l,
-				Width:     width,
+				Width:     LineWidth(widths, i),
 				Height:    textHeight,
 				GapBefore: leading,
 			})
 		}
 		if len(out) == 0 {
-			out = []TextLine{{Content: "", Width: width, Height: textHeight}}
+			out = []TextLine{{Content: "", Width: LineWidth(widths, 0), Height: textHeight}}
 		} else {
 			out[0].GapBefore = 0
 		}
 		return out, nil
 	}
-	lines, err := ts.LayoutLines(content, width, font, fontSize, lineHeight, wrap)
+	lines, err := ts.LayoutLines(content, widths, font, fontSize, lineHeight, wrap)
 	if err != nil {
 		return nil, err
 	}
@@ -1544,7 +1697,7 @@ func layoutLines(content string, width float64, font FontResource, fontSize, lin
 		if height <= 0 {
 			height = lineHeight
 		}
-		lines = []TextLine{{Content: "", Width: width, Height: height}}
+		lines = []TextLine{{Content: "", Width: LineWidth(widths, 0), Height: height}}
 	}
 	if len(lines) > 0 {
 		lines[0].GapBefore = 0
@@ -1670,6 +1823,9 @@ func parseRectShape(attrs map[string]string, res ResourceSet) (Rect, bool) {
 		c := resolveColor(v, res)
 		rc.FillColor = &c
 	}
+	if v := attrs["radius"]; v != "" {
+		rc.Radius = parseLength(v)
+	}
 	return rc, true
 }
 
@@ -1812,9 +1968,10 @@ func inferFlowWidth(block *dsl.Block, res ResourceSet, maxWidth float64, ts Type
 		return 0
 	}
 	var width float64
-	for _, stmt := range block.Statements {
+	// 估算宽度时同样展开控制语句；求值失败的分支在正式布局时会报告错误，这里忽略即可
+	_ = walkStatements(block, data, func(stmt *dsl.Statement, data any) error {
 		if stmt.Command == nil {
-			continue
+			return nil
 		}
 		switch stmt.Command.Name {
 		case "text":
@@ -1833,14 +1990,16 @@ func inferFlowWidth(block *dsl.Block, res ResourceSet, maxWidth float64, ts Type
 				}
 			}
 		case "table":
-			_, attrs := parseArgs(stmt.Command.Args, false)
+			_, args := splitTableSource(stmt.Command)
+			_, attrs := parseArgs(args, false)
 			if v := attrs["width"]; v != "" {
 				if w := parseDimension(v, maxWidth); w > width {
 					width = w
 				}
 			}
 		}
-	}
+		return nil
+	})
 	return width
 }
 
@@ -1853,10 +2012,13 @@ func inferTextWidth(cmd *dsl.Command, res ResourceSet, maxWidth float64, ts Type
 	if v := attrs["width"]; v != "" {
 		return parseDimension(v, maxWidth)
 	}
-	content := extractText(cmd.Block)
-	if data != nil {
-		content = binding.Interpolate(content, data)
-	}
+	content, _ := expandText(cmd.Block, data)
+	content, _ = parseInlineTypst(content)
+	return measureTextWidth(styleName, attrs, content, res, ts)
+}
+
+// measureTextWidth 返回纯文本不折行时的最大行宽（mm），无法用排版后端测量时退回估算。
+func measureTextWidth(styleName string, attrs map[string]string, content string, res ResourceSet, ts Typesetter) float64 {
 	if content == "" {
 		return 0
 	}
@@ -1895,7 +2057,7 @@ func inferTextWidth(cmd *dsl.Command, res ResourceSet, maxWidth float64, ts Type
 		}
 	}
 	// 使用极大宽度避免换行，获取每行实际宽度，取最大值
-	lines, err := layoutLines(content, math.MaxFloat64, fontRes, fontSizeMm, lineHeightMm, ts, "nowrap")
+	lines, err := layoutLines(content, []float64{math.MaxFloat64}, fontRes, fontSizeMm, lineHeightMm, ts, "nowrap")
 	if err != nil {
 		// 测量失败则退回估算
 		fontSize := parseFontSize(attrs["size"]) // pt
@@ -1,17 +1,21 @@
 package layout
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
+	"github.com/ByLCY/papyrus/binding"
 	"github.com/ByLCY/papyrus/dsl"
+	"github.com/ByLCY/papyrus/schema"
 )
 
 // buildWithRenderer 是测试辅助：用给定 DSL 文本构建布局结果。
 // stubTypesetter 是一个最小实现，仅用于测试，避免引入 renderer 造成循环依赖。
 type stubTypesetter struct{}
 
-func (s *stubTypesetter) LayoutLines(content string, width float64, font FontResource, fontSize float64, lineHeight float64, wrap string) ([]TextLine, error) {
+func (s *stubTypesetter) LayoutLines(content string, widths []float64, font FontResource, fontSize float64, lineHeight float64, wrap string) ([]TextLine, error) {
 	// 极简策略：按空格分词，尽量生成多行；不依赖具体宽度。
 	parts := strings.Fields(content)
 	if len(parts) == 0 {
@@ -260,3 +264,306 @@ func TestTextAlignAliases(t *testing.T) {
 		t.Fatalf("align end 未映射为 right: got=%q want=\"right\"", tb.Align)
 	}
 }
+
+// TestBuildMultiplePageSections 验证文档中的每个 page 段落都会按顺序排版，且各自使用独立的尺寸与页眉。
+func TestBuildMultiplePageSections(t *testing.T) {
+	dslText := `doc T v1 {
+  page A4 portrait margin 10mm {
+    header height 12mm { text { "封面页眉" } }
+    flow { text { "封面" } }
+  }
+  page A5 landscape margin 5mm {
+    flow { text { "正文" } }
+  }
+}`
+	res := buildWithRenderer(t, dslText, false)
+	if len(res.Pages) != 2 {
+		t.Fatalf("期望 2 页，实际 %d", len(res.Pages))
+	}
+	cover, body := res.Pages[0], res.Pages[1]
+	if !eq(cover.Width, 210) || !eq(cover.Height, 297) {
+		t.Fatalf("封面尺寸错误: %gx%g", cover.Width, cover.Height)
+	}
+	if !eq(body.Width, 210) || !eq(body.Height, 148) {
+		t.Fatalf("A5 横向尺寸错误: %gx%g", body.Width, body.Height)
+	}
+	if !eq(body.Margin.Top, 5) {
+		t.Fatalf("第二段落边距未独立生效: %+v", body.Margin)
+	}
+	if len(cover.Header.Texts) != 1 || len(body.Header.Texts) != 0 {
+		t.Fatalf("页眉不应跨段落共享: cover=%d body=%d", len(cover.Header.Texts), len(body.Header.Texts))
+	}
+	if len(body.Texts) != 1 || body.Texts[0].Content != "正文" {
+		t.Fatalf("第二段落内容缺失: %+v", body.Texts)
+	}
+}
+
+// TestPageSetTemplate 验证 page use 会套用 page-set 的尺寸、边距、页眉、背景形状与样式，并允许页面级覆盖。
+func TestPageSetTemplate(t *testing.T) {
+	dslText := `doc T v1 {
+  resources {
+    style Body { size: 12pt color: #333 }
+  }
+  page-set Letterhead {
+    size: A5
+    orientation: portrait
+    margin: [10mm, 8mm]
+    header height 12mm { text { "信头" } }
+    rect x 0mm y 0mm width 5mm height 5mm fill #ff0000
+    style Body { size: 10pt }
+  }
+  page use Letterhead {
+    flow {
+      text Body size 120mm { "A" }
+      text Body size 120mm { "B" }
+    }
+  }
+  page use Letterhead landscape margin 5mm {
+    header { text { "覆盖后的页眉" } }
+    flow { text Body { "C" } }
+  }
+}`
+	res := buildWithRenderer(t, dslText, false)
+	if len(res.Pages) != 3 {
+		t.Fatalf("期望 3 页，实际 %d", len(res.Pages))
+	}
+	first := res.Pages[0]
+	if !eq(first.Width, 148) || !eq(first.Height, 210) {
+		t.Fatalf("模板尺寸未生效: %gx%g", first.Width, first.Height)
+	}
+	if !(eq(first.Margin.Top, 10) && eq(first.Margin.Left, 8)) {
+		t.Fatalf("模板边距未生效: %+v", first.Margin)
+	}
+	for i := 0; i < 2; i++ {
+		p := res.Pages[i]
+		if len(p.Rects) != 1 || len(p.Header.Texts) != 1 || p.Header.Texts[0].Content != "信头" {
+			t.Fatalf("第 %d 页缺少模板背景或页眉: rects=%d header=%+v", i+1, len(p.Rects), p.Header.Texts)
+		}
+	}
+	last := res.Pages[2]
+	if !eq(last.Width, 210) || !eq(last.Height, 148) || !eq(last.Margin.Top, 5) {
+		t.Fatalf("页面级覆盖未生效: %gx%g %+v", last.Width, last.Height, last.Margin)
+	}
+	if len(last.Header.Texts) != 1 || last.Header.Texts[0].Content != "覆盖后的页眉" {
+		t.Fatalf("页面级 header 应覆盖模板: %+v", last.Header.Texts)
+	}
+	if len(last.Texts) != 1 || !eq(last.Texts[0].FontSize, 10*0.352777) {
+		t.Fatalf("模板样式未合并到页面: %+v", last.Texts)
+	}
+	if c := last.Texts[0].Color; c.R != 0x33 {
+		t.Fatalf("模板样式应仅覆盖声明的属性: %+v", c)
+	}
+}
+
+// TestTextExpressions 验证文本与 meta 中的 ${} 表达式求值，以及表达式错误携带 DSL 源码位置。
+func TestTextExpressions(t *testing.T) {
+	dslText := `doc T v1 {
+  meta { title: "Invoice ${data.no}" }
+  page A4 portrait margin 10mm {
+    flow {
+      for item in items {
+        if item.qty * item.price >= 100 && !item.free {
+          text { "${item.name}: ${item.qty * item.price}" }
+        } else {
+          text { "${item.name}: ${item.free ? '免费' : '少量'}" }
+        }
+      }
+      text { "${missing.path}" }
+    }
+  }
+}`
+	data := map[string]any{
+		"no": "N-1",
+		"items": []any{
+			map[string]any{"name": "A", "qty": 3.0, "price": 40.0},
+			map[string]any{"name": "B", "qty": 1.0, "price": 20.0, "free": true},
+		},
+	}
+	res := buildWithData(t, dslText, data)
+	if res.Meta.Title != "Invoice N-1" {
+		t.Fatalf("meta 标题插值错误: %q", res.Meta.Title)
+	}
+	got := strings.Join(pageTexts(res.Pages[0]), "|")
+	want := "A: 120|B: 免费|${missing.path}"
+	if got != want {
+		t.Fatalf("文本表达式结果错误:\n got=%s\nwant=%s", got, want)
+	}
+
+	doc, err := dsl.Parse(strings.NewReader("doc T v1 {\n  page A4 {\n    flow {\n      text { \"x ${data.qty - }\" }\n    }\n  }\n}"))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	_, err = Build(doc, data, BuildOptions{Typesetter: &stubTypesetter{}})
+	if err == nil || !strings.HasPrefix(err.Error(), "4:") {
+		t.Fatalf("表达式错误应带有行号，实际 %v", err)
+	}
+}
+
+// TestBuildOptionsFuncs 验证 BuildOptions.Funcs 注册的函数可在正文、循环与页眉中调用，并与内置函数共存。
+func TestBuildOptionsFuncs(t *testing.T) {
+	dslText := `doc T v1 {
+  page A4 portrait margin 10mm {
+    header { text { "${brand()}" } }
+    flow {
+      for item in items {
+        text { "${item.name}: ${money(item.price)} / ${formatMoney(item.price, '¥')}" }
+      }
+    }
+  }
+}`
+	doc, err := dsl.Parse(strings.NewReader(dslText))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	data := map[string]any{"items": []any{map[string]any{"name": "A", "price": 1999.5}}}
+	opts := BuildOptions{
+		Typesetter: &stubTypesetter{},
+		Funcs: binding.FuncMap{
+			"brand": func() string { return "ACME" },
+			"money": func(v float64) string { return fmt.Sprintf("USD %.0f", v) },
+		},
+	}
+	res, err := Build(doc, data, opts)
+	if err != nil {
+		t.Fatalf("布局计算失败: %v", err)
+	}
+	if got := res.Pages[0].Header.Texts[0].Content; got != "ACME" {
+		t.Fatalf("页眉中的自定义函数结果错误: %q", got)
+	}
+	if got := res.Pages[0].Texts[0].Content; got != "A: USD 2000 / ¥1,999.50" {
+		t.Fatalf("正文中的函数调用结果错误: %q", got)
+	}
+
+	_, err = Build(doc, data, BuildOptions{Typesetter: &stubTypesetter{}})
+	if err == nil || !strings.Contains(err.Error(), "brand") {
+		t.Fatalf("未注册的函数应报错，实际 %v", err)
+	}
+}
+
+// TestMissingBindingPolicy 验证 BuildOptions.MissingBinding 的三种策略与 Result.Unresolved 中的源码位置。
+func TestMissingBindingPolicy(t *testing.T) {
+	dslText := "doc T v1 {\n  meta { title: \"T ${data.no}\" }\n  page A4 {\n    flow {\n      for item in items {\n        text { \"${item.name}:${item.sku}\" }\n      }\n    }\n  }\n}"
+	doc, err := dsl.Parse(strings.NewReader(dslText))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	data := map[string]any{"items": []any{map[string]any{"name": "A"}, map[string]any{"name": "B"}}}
+
+	keep, err := Build(doc, data, BuildOptions{Typesetter: &stubTypesetter{}})
+	if err != nil {
+		t.Fatalf("布局计算失败: %v", err)
+	}
+	if got := keep.Pages[0].Texts[0].Content; got != "A:${item.sku}" {
+		t.Fatalf("keep 策略应保留占位符，实际 %q", got)
+	}
+	if len(keep.Unresolved) != 2 {
+		t.Fatalf("应收集 2 处未解析绑定（循环中去重），实际 %v", keep.Unresolved)
+	}
+	if u := keep.Unresolved[0]; u.Path != "data.no" || u.Pos.Line != 2 || u.Pos.Column != 22 {
+		t.Fatalf("meta 中的未解析绑定位置错误: %+v", u)
+	}
+	if u := keep.Unresolved[1]; u.Path != "item.sku" || u.Pos.Line != 6 || u.Pos.Column != 32 {
+		t.Fatalf("文本中的未解析绑定位置错误: %+v", u)
+	}
+
+	empty, err := Build(doc, data, BuildOptions{Typesetter: &stubTypesetter{}, MissingBinding: binding.MissingEmpty})
+	if err != nil {
+		t.Fatalf("布局计算失败: %v", err)
+	}
+	if got := empty.Pages[0].Texts[1].Content; got != "B:" || empty.Meta.Title != "T " {
+		t.Fatalf("empty 策略应输出空字符串，实际 %q / %q", got, empty.Meta.Title)
+	}
+
+	_, err = Build(doc, data, BuildOptions{Typesetter: &stubTypesetter{}, MissingBinding: binding.MissingError})
+	var unresolved *binding.UnresolvedError
+	if !errors.As(err, &unresolved) || len(unresolved.Unresolved) != 2 {
+		t.Fatalf("error 策略应返回包含全部未解析绑定的错误，实际 %v", err)
+	}
+	if !strings.Contains(err.Error(), "6:32") {
+		t.Fatalf("错误信息应包含源码位置，实际 %v", err)
+	}
+}
+
+// TestBuildSchemaValidation 验证 BuildOptions.Schema 在布局前校验数据，错误带 JSON Pointer。
+func TestBuildSchemaValidation(t *testing.T) {
+	doc, err := dsl.Parse(strings.NewReader("doc T v1 {\n  page A4 {\n    flow { text { \"${user.name}\" } }\n  }\n}"))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	s, err := schema.Infer(doc)
+	if err != nil {
+		t.Fatalf("推断 Schema 失败: %v", err)
+	}
+	opts := BuildOptions{Typesetter: &stubTypesetter{}, Schema: s}
+	if _, err := Build(doc, map[string]any{"user": map[string]any{"name": "A"}}, opts); err != nil {
+		t.Fatalf("合法数据不应报错: %v", err)
+	}
+	_, err = Build(doc, map[string]any{"user": map[string]any{}}, opts)
+	var verr *schema.ValidationError
+	if !errors.As(err, &verr) || len(verr.Violations) != 1 || verr.Violations[0].Pointer != "/user/name" {
+		t.Fatalf("期望 /user/name 缺失的校验错误，实际 %v", err)
+	}
+}
+
+// TestInterpolationEscaping 验证插值数据中的 #、\ 与方括号按字面输出，只有模板中的标记生效；raw() 可插入可信标记。
+func TestInterpolationEscaping(t *testing.T) {
+	dslText := `doc T v1 {
+  page A4 {
+    flow {
+      text { "#underline[${name}] ${path}" }
+      text { "${raw(note)}" }
+    }
+  }
+}`
+	data := map[string]any{
+		"name": "#underline[x]] \\# tail",
+		"path": `C:\dir\#1`,
+		"note": "#underline[可信]",
+	}
+	res := buildWithData(t, dslText, data)
+	texts := res.Pages[0].Texts
+	want := `#underline[x]] \# tail C:\dir\#1`
+	if got := texts[0].Content; got != want {
+		t.Fatalf("插值数据应按字面输出:\n got=%q\nwant=%q", got, want)
+	}
+	if n := underlinedRunes(texts[0]); n != len([]rune(data["name"].(string))) {
+		t.Fatalf("下划线应只覆盖模板中的 #underline[...]，实际覆盖 %d 个字符", n)
+	}
+	if got := texts[1].Content; got != "可信" || underlinedRunes(texts[1]) != 2 {
+		t.Fatalf("raw() 的结果应按标记解析，实际 %q", got)
+	}
+}
+
+func underlinedRunes(tb TextBox) int {
+	n := 0
+	for _, line := range tb.Lines {
+		for _, sp := range line.Spans {
+			if sp.Underline {
+				n += sp.Length
+			}
+		}
+	}
+	return n
+}
+
+// TestUnderlineSpanAcrossLines 验证跨行的下划线区间按行截断，每行的区间不超过该行内容。
+func TestUnderlineSpanAcrossLines(t *testing.T) {
+	dslText := `doc T v1 {
+  page A4 {
+    flow {
+      text { "#underline[a b c d e f]" }
+    }
+  }
+}`
+	res := buildWithRenderer(t, dslText, false)
+	lines := res.Pages[0].Texts[0].Lines
+	if len(lines) != 3 {
+		t.Fatalf("期望 3 行，实际 %d", len(lines))
+	}
+	for i, line := range lines {
+		n := len([]rune(line.Content))
+		if len(line.Spans) != 1 || line.Spans[0].Start != 0 || line.Spans[0].Length != n {
+			t.Fatalf("第 %d 行下划线应恰好覆盖整行 %q: %+v", i, line.Content, line.Spans)
+		}
+	}
+}
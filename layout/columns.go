@@ -0,0 +1,182 @@
+package layout
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// 分栏容器：`columns 2 gap 8mm { ... }` 将子块依次排入等宽的各栏：当前栏排满（到内容区域底部）后换到下一栏，
+// 最后一栏排满后换页，从新页的第一栏继续。balance: true 时调整最后一页各栏的高度使其尽量相等。
+// 不分页的上下文（absolute、单元格）中只使用第一栏。
+
+// 平衡栏高时二分查找的次数上限，以及栏高区间缩小到 balanceTolerance（mm）以内即停止。
+// 每次查找都要重新排版整个分栏，因此次数不宜过多。
+const (
+	balanceIterations = 10
+	balanceTolerance  = 0.5
+)
+
+// columnState 是分栏容器在当前页上的状态。
+type columnState struct {
+	count  int
+	index  int     // 当前栏
+	gap    float64 // 栏间距
+	left   float64 // 第一栏的横坐标
+	top    float64 // 当前页上各栏的起始纵坐标
+	bottom float64 // 当前页上已排各栏的最低纵坐标
+	// limit 大于 0 时限制第 limitPage 页上各栏的高度，用于平衡栏高。
+	limit     float64
+	limitPage int
+	// ends 记录当前页上各栏排满时页面各类元素的数量，用于判断块之后的内容是否留在同一栏。
+	ends [][6]int
+}
+
+// next 换到下一栏；已是最后一栏时返回 false，由调用方换页。
+func (c *columnState) next(ctx *flowContext) bool {
+	// 块尚未放置任何内容就换栏时，其上边距不计入本栏
+	bottom := ctx.cursorY
+	if bottom == ctx.blockTop {
+		bottom = ctx.blockStart
+	}
+	c.bottom = math.Max(c.bottom, bottom)
+	if c.index+1 >= c.count {
+		return false
+	}
+	c.ends = append(c.ends[:c.index], ctx.collector.curr().size())
+	c.index++
+	ctx.baseX = c.left + float64(c.index)*(ctx.width+c.gap)
+	ctx.baseY, ctx.cursorY, ctx.spaceAfter = c.top, c.top, 0
+	return true
+}
+
+// reset 在换页后从新页的第一栏开始。
+func (c *columnState) reset(ctx *flowContext) {
+	c.index, c.ends = 0, nil
+	c.top, c.bottom = ctx.cursorY, ctx.cursorY
+	ctx.baseX = c.left
+}
+
+// contentBottom 返回当前位置可用的内容底部；平衡栏高时受所在分栏的高度限制。
+func (ctx *flowContext) contentBottom() float64 {
+	bottom := ctx.collector.maxContentY()
+	for c := ctx; c != nil; c = c.parent {
+		if s := c.columns; s != nil && s.limit > 0 && s.limitPage == ctx.collector.current {
+			bottom = math.Min(bottom, s.top+s.limit)
+		}
+	}
+	return bottom
+}
+
+// contentTop 返回当前栏（不在分栏中时为页面内容区域）的顶部。
+func (ctx *flowContext) contentTop() float64 {
+	for c := ctx; c != nil; c = c.parent {
+		if c.columns != nil {
+			return c.columns.top
+		}
+	}
+	return ctx.collector.contentTop()
+}
+
+// atTop 判断光标是否位于当前栏（页面内容区域）的顶部，此时换栏或换页无济于事。
+func (ctx *flowContext) atTop() bool {
+	return ctx.cursorY <= ctx.contentTop()+1e-9
+}
+
+// splitColumnCount 取出 columns 后紧跟的栏数，返回栏数与其余参数。
+func splitColumnCount(args []*dsl.Lexeme) (string, []*dsl.Lexeme) {
+	if len(args) > 0 && args[0].Type == "Number" {
+		return args[0].Value, args[1:]
+	}
+	return "", args
+}
+
+func handleColumns(cmd *dsl.Command, parent *flowContext, res ResourceSet) error {
+	if cmd.Block == nil {
+		return fmt.Errorf("columns 语句缺少内容")
+	}
+	countValue, args := splitColumnCount(cmd.Args)
+	styleName, attrs := parseArgs(args, false)
+	attrs = mergeStyleAttributes(styleName, attrs, res.Styles)
+	if countValue == "" {
+		countValue = attrs["count"]
+	}
+	count := 2
+	if countValue != "" {
+		n, err := strconv.Atoi(countValue)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("columns 栏数必须为正整数：%s", countValue)
+		}
+		count = n
+	}
+	gap := math.Max(parseLength(attrs["gap"]), 0)
+	width := math.Max((parent.width-gap*float64(count-1))/float64(count), 0)
+	before, after := blockMargins(attrs, res)
+
+	parent.beginBlock(before)
+	start := parent.mark()
+	layout := func(limit float64, limitPage int) (*flowContext, error) {
+		child := &flowContext{
+			baseX:          parent.baseX,
+			baseY:          parent.cursorY,
+			width:          width,
+			cursorY:        parent.cursorY,
+			data:           parent.data,
+			typesetter:     parent.typesetter,
+			debug:          parent.debug,
+			parent:         parent,
+			collector:      parent.collector,
+			margin:         parent.margin,
+			allowPageBreak: parent.allowPageBreak,
+			textAlign:      parent.textAlign,
+			textWrap:       parent.textWrap,
+			columns: &columnState{
+				count:     count,
+				gap:       gap,
+				left:      parent.baseX,
+				top:       parent.cursorY,
+				bottom:    parent.cursorY,
+				limit:     limit,
+				limitPage: limitPage,
+			},
+		}
+		return child, processBlock(cmd.Block, child, res)
+	}
+	child, err := layout(0, 0)
+	if err != nil {
+		return err
+	}
+
+	// 平衡栏高：二分查找最后一页上使内容仍能排入该页各栏的最小栏高，再按该栏高重新排版。
+	// 内容只占第一栏时，以已用高度为上限，使其分摊到各栏。
+	if c := child.columns; isEnabled(attrs["balance"]) && parent.allowPageBreak && parent.collector != nil &&
+		c.count > 1 && (c.index > 0 || child.cursorY > c.top) {
+		page := parent.collector.current
+		lo, hi := 0.0, parent.collector.maxContentY()-c.top
+		if c.index == 0 {
+			hi = math.Min(child.cursorY-c.top+balanceTolerance, hi)
+		}
+		for i := 0; i < balanceIterations && hi-lo > balanceTolerance; i++ {
+			mid := (lo + hi) / 2
+			parent.restore(start)
+			if _, err := layout(mid, page); err != nil {
+				return err
+			}
+			if parent.collector.current == page {
+				hi = mid
+			} else {
+				lo = mid
+			}
+		}
+		parent.restore(start)
+		if child, err = layout(hi, page); err != nil {
+			return err
+		}
+	}
+
+	parent.cursorY = math.Max(child.columns.bottom, child.cursorY)
+	parent.endBlock(after)
+	return nil
+}
@@ -0,0 +1,118 @@
+package layout
+
+import "testing"
+
+// TestColumnsFlow 验证分栏：第一栏排满后换到下一栏，最后一栏排满后换页，栏中的图片随栏平移。
+func TestColumnsFlow(t *testing.T) {
+	dslText := `doc T v1 {
+  resources {
+    font Body { src: "x.ttf" }
+    style Big { font: Body; size: 100mm }
+    image Logo { src: "logo.png" }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      columns 2 gap 10mm {
+        text Big { "a" }
+        text Big { "b" }
+        text Big { "c" }
+        text Big { "d" }
+        image Logo width 30mm height 20mm
+        text Big { "e" }
+      }
+    }
+  }
+}`
+	res := buildWithData(t, dslText, nil)
+	if got := pagesTexts(res); len(got) != 2 || len(got[0]) != 4 || len(got[1]) != 1 {
+		t.Fatalf("分页结果不符: %v", got)
+	}
+	want := map[string][2]float64{"a": {10, 10}, "b": {10, 113}, "c": {110, 10}, "d": {110, 113}}
+	for _, tb := range res.Pages[0].Texts {
+		w := want[tb.Content]
+		if !eq(tb.X, w[0]) || !eq(tb.Y, w[1]) || !eq(tb.Width, 90) {
+			t.Fatalf("%s 的位置不符: x=%.2f y=%.2f w=%.2f, want %v", tb.Content, tb.X, tb.Y, tb.Width, w)
+		}
+	}
+	if imgs := res.Pages[0].Images; len(imgs) != 1 || !eq(imgs[0].X, 110) || !eq(imgs[0].Y, 216) {
+		t.Fatalf("图片应位于第二栏: %+v", imgs)
+	}
+	if e := res.Pages[1].Texts[0]; !eq(e.X, 10) || !eq(e.Y, 10) {
+		t.Fatalf("换页后应从第一栏开始: x=%.2f y=%.2f", e.X, e.Y)
+	}
+}
+
+// TestColumnsBalance 验证 balance: true 使最后一页各栏高度相等（误差不超过 balanceTolerance），flow 背景按栏分段。
+func TestColumnsBalance(t *testing.T) {
+	dslText := `doc T v1 {
+  resources {
+    font Body { src: "x.ttf" }
+    style Big { font: Body; size: 80mm }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      columns 2 gap 10mm balance true {
+        flow background #eee {
+          text Big { "a" }
+          text Big { "b" }
+          text Big { "c" }
+          text Big { "d" }
+        }
+      }
+      text Big { "after" }
+    }
+  }
+}`
+	res := buildWithData(t, dslText, nil)
+	if len(res.Pages) != 1 {
+		t.Fatalf("期望 1 页，got %d", len(res.Pages))
+	}
+	want := map[string][2]float64{"a": {10, 10}, "b": {10, 93}, "c": {110, 10}, "d": {110, 93}, "after": {10, 176}}
+	for _, tb := range res.Pages[0].Texts {
+		w := want[tb.Content]
+		if !eq(tb.X, w[0]) || !eq(tb.Y, w[1]) {
+			t.Fatalf("%s 的位置不符: x=%.2f y=%.2f, want %v", tb.Content, tb.X, tb.Y, w)
+		}
+	}
+	rects := res.Pages[0].Rects
+	if len(rects) != 2 {
+		t.Fatalf("期望每栏一段背景，got %d", len(rects))
+	}
+	for i, x := range []float64{10, 110} {
+		if rc := rects[i]; !eq(rc.X, x) || !eq(rc.Y, 10) || rc.Height < 163-0.01 || rc.Height > 163+balanceTolerance {
+			t.Fatalf("第 %d 栏的背景不符: %+v", i+1, rc)
+		}
+	}
+}
+
+// TestColumnsBalanceSingleColumn 验证内容排得进第一栏时，balance 同样把内容分摊到各栏。
+func TestColumnsBalanceSingleColumn(t *testing.T) {
+	dslText := `doc T v1 {
+  resources {
+    font Body { src: "x.ttf" }
+    style Small { font: Body; size: 20mm }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      columns 2 gap 10mm balance yes {
+        text Small { "a" }
+        text Small { "b" }
+        text Small { "c" }
+        text Small { "d" }
+      }
+      text Small { "after" }
+    }
+  }
+}`
+	res := buildWithData(t, dslText, nil)
+	if len(res.Pages) != 1 {
+		t.Fatalf("期望 1 页，got %d", len(res.Pages))
+	}
+	want := map[string][2]float64{"a": {10, 10}, "b": {10, 33}, "c": {110, 10}, "d": {110, 33}, "after": {10, 56}}
+	for _, tb := range res.Pages[0].Texts {
+		w := want[tb.Content]
+		if !eq(tb.X, w[0]) || !eq(tb.Y, w[1]) {
+			t.Fatalf("%s 的位置不符: x=%.2f y=%.2f, want %v", tb.Content, tb.X, tb.Y, w)
+		}
+	}
+}
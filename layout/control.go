@@ -0,0 +1,176 @@
+package layout
+
+import (
+	"fmt"
+
+	"github.com/ByLCY/papyrus/binding"
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// 该文件实现布局阶段的控制语句：let / if / elif / else / for。
+// 控制语句在遍历 block 时展开，循环变量与 let 别名通过 binding.Scope 逐层传递。
+
+// chainState 记录 if/elif/else 链的求值状态。
+type chainState int
+
+const (
+	chainNone    chainState = iota // 不在条件链中
+	chainPending                   // 条件链中尚无分支命中
+	chainTaken                     // 条件链中已有分支命中
+)
+
+// walkStatements 依次遍历 block 中的语句并展开控制语句，其余语句交给 visit 处理。
+// visit 收到的 data 为语句所在的作用域（*binding.Scope），调用方应使用它进行插值与求值。
+func walkStatements(block *dsl.Block, data any, visit func(stmt *dsl.Statement, data any) error) error {
+	if block == nil {
+		return nil
+	}
+	scope := binding.ScopeOf(data)
+	chain := chainNone
+	for _, stmt := range block.Statements {
+		cmd := stmt.Command
+		if cmd == nil {
+			chain = chainNone
+			if err := visit(stmt, scope); err != nil {
+				return err
+			}
+			continue
+		}
+		switch cmd.Name {
+		case "let":
+			chain = chainNone
+			name, val, err := evalLet(cmd, scope)
+			if err != nil {
+				return err
+			}
+			// let 之后的语句使用新的子作用域，保证别名只读且不泄漏到外层 block
+			scope = scope.Child()
+			scope.Set(name, val)
+		case "if":
+			ok, err := evalCondition(cmd, scope)
+			if err != nil {
+				return err
+			}
+			chain = chainPending
+			if ok {
+				chain = chainTaken
+				if err := walkStatements(cmd.Block, scope.Child(), visit); err != nil {
+					return err
+				}
+			}
+		case "elif":
+			if chain == chainNone {
+				return fmt.Errorf("%s: elif 前缺少 if", cmd.Pos)
+			}
+			if chain == chainTaken {
+				continue
+			}
+			ok, err := evalCondition(cmd, scope)
+			if err != nil {
+				return err
+			}
+			if ok {
+				chain = chainTaken
+				if err := walkStatements(cmd.Block, scope.Child(), visit); err != nil {
+					return err
+				}
+			}
+		case "else":
+			if chain == chainNone {
+				return fmt.Errorf("%s: else 前缺少 if", cmd.Pos)
+			}
+			taken := chain == chainTaken
+			chain = chainNone
+			if taken {
+				continue
+			}
+			if err := walkStatements(cmd.Block, scope.Child(), visit); err != nil {
+				return err
+			}
+		case "for":
+			chain = chainNone
+			if err := walkFor(cmd, scope, visit); err != nil {
+				return err
+			}
+		default:
+			chain = chainNone
+			if err := visit(stmt, scope); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// walkFor 展开 `for item in expr { ... }`，每次迭代在独立作用域中绑定 item 与 loop。
+// loop 提供 index（从 1 开始）、index0（从 0 开始）、first、last 与 length。
+func walkFor(cmd *dsl.Command, scope *binding.Scope, visit func(stmt *dsl.Statement, data any) error) error {
+	if len(cmd.Args) < 3 || cmd.Args[0].Type != "Ident" || cmd.Args[1].Value != "in" {
+		return fmt.Errorf("%s: for 语句格式应为 for <变量> in <表达式>", cmd.Pos)
+	}
+	if cmd.Block == nil {
+		return fmt.Errorf("%s: for 语句缺少循环体", cmd.Pos)
+	}
+	name := cmd.Args[0].Value
+	val, err := evalArgs(cmd, 2, scope)
+	if err != nil {
+		return err
+	}
+	items, ok := binding.Items(val)
+	if !ok {
+		return fmt.Errorf("%s: for 只能遍历数组，实际为 %T", cmd.Args[2].Pos, val)
+	}
+	for i, item := range items {
+		iter := scope.Child()
+		iter.Set(name, item)
+		iter.Set("loop", loopMeta(i, len(items)))
+		if err := walkStatements(cmd.Block, iter, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loopMeta 返回第 i 次迭代（共 n 次）的 loop 元信息。
+func loopMeta(i, n int) map[string]interface{} {
+	return map[string]interface{}{
+		"index":  i + 1,
+		"index0": i,
+		"first":  i == 0,
+		"last":   i == n-1,
+		"length": n,
+	}
+}
+
+// evalLet 解析 `let name = expr` 并返回绑定的值。
+func evalLet(cmd *dsl.Command, scope *binding.Scope) (string, any, error) {
+	if len(cmd.Args) < 3 || cmd.Args[0].Type != "Ident" || cmd.Args[1].Value != "=" {
+		return "", nil, fmt.Errorf("%s: let 语句格式应为 let <名称> = <表达式>", cmd.Pos)
+	}
+	val, err := evalArgs(cmd, 2, scope)
+	if err != nil {
+		return "", nil, err
+	}
+	return cmd.Args[0].Value, val, nil
+}
+
+// evalCondition 对 if/elif 的条件求值。
+func evalCondition(cmd *dsl.Command, scope *binding.Scope) (bool, error) {
+	if len(cmd.Args) == 0 {
+		return false, fmt.Errorf("%s: %s 语句缺少条件", cmd.Pos, cmd.Name)
+	}
+	val, err := evalArgs(cmd, 0, scope)
+	if err != nil {
+		return false, err
+	}
+	return binding.Truthy(val), nil
+}
+
+// evalArgs 将 cmd.Args[from:] 作为表达式求值；表达式 AST 缓存在命令上，循环中只解析一次。
+func evalArgs(cmd *dsl.Command, from int, scope *binding.Scope) (any, error) {
+	node, err := cmd.Expr(from)
+	if err != nil {
+		return nil, err
+	}
+	return binding.Eval(node, scope)
+}
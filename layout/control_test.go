@@ -0,0 +1,158 @@
+package layout
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+func buildWithData(t *testing.T, dslText string, data any) *Result {
+	t.Helper()
+	doc, err := dsl.Parse(strings.NewReader(dslText))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	res, err := Build(doc, data, BuildOptions{Typesetter: &stubTypesetter{}})
+	if err != nil {
+		t.Fatalf("布局计算失败: %v", err)
+	}
+	return res
+}
+
+func pageTexts(p Page) []string {
+	out := make([]string, 0, len(p.Texts))
+	for _, tb := range p.Texts {
+		out = append(out, tb.Content)
+	}
+	return out
+}
+
+// TestControlStatements 验证 let/for/if/elif/else 在布局阶段按数据展开，且循环变量与 loop 元信息可用于插值。
+func TestControlStatements(t *testing.T) {
+	dslText := `doc T v1 {
+  page A4 portrait margin 10mm {
+    header { for item in data.items { text { "H${loop.index}" } } }
+    flow {
+      let currency = data.meta.currency
+      for item in data.items {
+        if loop.first {
+          text { "first:${item.name}" }
+        } elif item.qty > 1 {
+          text { "many:${item.name} ${currency}" }
+        } else {
+          text { "last:${item.name}:${loop.last}" }
+        }
+      }
+      if data.summary != nil {
+        text { "summary" }
+      }
+      if !data.items {
+        text { "empty" }
+      }
+    }
+  }
+}`
+	data := map[string]any{
+		"meta": map[string]any{"currency": "CNY"},
+		"items": []any{
+			map[string]any{"name": "A", "qty": 1.0},
+			map[string]any{"name": "B", "qty": 3.0},
+			map[string]any{"name": "C", "qty": 1.0},
+		},
+	}
+	res := buildWithData(t, dslText, data)
+	got := strings.Join(pageTexts(res.Pages[0]), "|")
+	want := "first:A|many:B CNY|last:C:true"
+	if got != want {
+		t.Fatalf("控制语句展开结果错误:\n got=%s\nwant=%s", got, want)
+	}
+	if n := len(res.Pages[0].Header.Texts); n != 3 {
+		t.Fatalf("header 中的 for 应生成 3 段文本，实际 %d", n)
+	}
+}
+
+// TestControlStatementsInTable 验证 for 可用于生成表格行与行内单元格。
+func TestControlStatementsInTable(t *testing.T) {
+	dslText := `doc T v1 {
+  page A4 portrait margin 10mm {
+    flow {
+      table columns 2 {
+        header { cell { "名称" } cell { "数量" } }
+        for item in data.items {
+          row {
+            for v in item.values { cell { "${v}" } }
+          }
+        }
+      }
+    }
+  }
+}`
+	data := map[string]any{
+		"items": []any{
+			map[string]any{"values": []any{"a", 1.0}},
+			map[string]any{"values": []any{"b", 2.0}},
+		},
+	}
+	res := buildWithData(t, dslText, data)
+	if len(res.Pages[0].Tables) != 1 {
+		t.Fatalf("未生成表格")
+	}
+	rows := res.Pages[0].Tables[0].Rows
+	if len(rows) != 3 {
+		t.Fatalf("期望 1 行表头 + 2 行数据，实际 %d", len(rows))
+	}
+	if c := rows[2].Cells[0].Text.Content; c != "b" {
+		t.Fatalf("循环生成的单元格内容错误: %q", c)
+	}
+}
+
+// TestControlStatementErrors 验证格式错误的控制语句会返回带位置信息的错误。
+func TestControlStatementErrors(t *testing.T) {
+	cases := map[string]string{
+		"elif 无 if": `doc T v1 { page A4 { flow { elif data.x { text { "x" } } } } }`,
+		"for 非数组":   `doc T v1 { page A4 { flow { for x in data.name { text { "x" } } } } }`,
+	}
+	for name, dslText := range cases {
+		doc, err := dsl.Parse(strings.NewReader(dslText))
+		if err != nil {
+			t.Fatalf("%s: 解析 DSL 失败: %v", name, err)
+		}
+		_, err = Build(doc, map[string]any{"name": "n"}, BuildOptions{Typesetter: &stubTypesetter{}})
+		if err == nil || !strings.Contains(err.Error(), "1:") {
+			t.Fatalf("%s: 期望带位置的错误，实际 %v", name, err)
+		}
+	}
+}
+
+// TestControlStatementsWithStructs 验证 layout.Build 可直接绑定 Go 结构体（json 标签、类型化切片与指针）。
+func TestControlStatementsWithStructs(t *testing.T) {
+	type line struct {
+		Name  string  `json:"name"`
+		Price float64 `json:"price"`
+	}
+	type invoice struct {
+		No    string  `json:"no"`
+		Lines []*line `json:"lines"`
+		Note  *string `json:"note"`
+	}
+	dslText := `doc T v1 {
+  page A4 portrait margin 10mm {
+    flow {
+      text { "${no}" }
+      for l in data.lines {
+        text { "${loop.index}.${l.name}=${formatMoney(l.price)}" }
+      }
+      if note == nil {
+        text { "无备注" }
+      }
+    }
+  }
+}`
+	res := buildWithData(t, dslText, &invoice{No: "N-9", Lines: []*line{{Name: "A", Price: 1200}, {Name: "B", Price: 3.5}}})
+	got := strings.Join(pageTexts(res.Pages[0]), "|")
+	want := "N-9|1.A=1,200.00|2.B=3.50|无备注"
+	if got != want {
+		t.Fatalf("结构体绑定结果错误:\n got=%s\nwant=%s", got, want)
+	}
+}
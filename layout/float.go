@@ -0,0 +1,103 @@
+package layout
+
+import (
+	"math"
+)
+
+// 浮动图片：flow 中的 `image Photo width 40mm float left` 放在当前光标处的左侧（或右侧）而不推进光标，
+// 之后的 text 与浮动区域在纵向上重叠的行让出图片宽度加 gap（默认 defaultFloatGap）的位置，绕图排版。
+// 其他块级内容（flow、table、grid、row、columns 与非浮动图片）不参与绕排，从浮动区域下方开始；
+// 换页、换栏以及所在 flow 结束时同样越过浮动区域。
+
+const defaultFloatGap = 3.0
+
+// floatArea 是浮动图片占用的区域，底部与宽度均含 gap。
+type floatArea struct {
+	right       bool
+	top, bottom float64
+	width       float64
+}
+
+// lineIndent 是一行左右两侧让出的宽度。
+type lineIndent struct {
+	left, right float64
+}
+
+// placeFloat 将图片作为浮动元素放在当前光标处；同侧已有浮动区域时放在其下方。
+// 放不下时换页，除非图片已位于页（栏）顶部，换页也无济于事。
+func placeFloat(ctx *flowContext, img ImageBox, right bool, gap float64) {
+	top := ctx.floatTop(right)
+	if ctx.allowPageBreak && ctx.collector != nil && top+img.Height > ctx.contentBottom() && (!ctx.atTop() || top > ctx.cursorY) {
+		ctx.pageBreak()
+		top = ctx.floatTop(right)
+	}
+	img.X, img.Y = ctx.baseX, top
+	if right {
+		img.X = ctx.baseX + ctx.width - img.Width
+	}
+	if acc := ctx.acc(); acc != nil {
+		acc.appendImage(img)
+	}
+	ctx.floats = append(ctx.floats, floatArea{right: right, top: top, bottom: top + img.Height + gap, width: img.Width + gap})
+}
+
+// floatTop 返回新浮动元素的顶部：光标处（含块间距），同侧已有浮动区域时为其底部。
+func (ctx *flowContext) floatTop(right bool) float64 {
+	top := ctx.cursorY + ctx.spaceAfter
+	for _, f := range ctx.floats {
+		if f.right == right && f.bottom > top {
+			top = f.bottom
+		}
+	}
+	return top
+}
+
+// clearFloats 将光标移到所有浮动区域的下方，结束绕排。
+func (ctx *flowContext) clearFloats() {
+	for _, f := range ctx.floats {
+		if f.bottom > ctx.cursorY {
+			ctx.cursorY, ctx.spaceAfter = f.bottom, 0
+		}
+	}
+	ctx.floats = nil
+}
+
+// floatIndents 返回从光标处开始排版的文本框各行让出的宽度，直到越过所有浮动区域；
+// 行距取自按整宽排版的 tb。某行剩余宽度容不下两个字号宽时返回 false，此时应先越过浮动区域再排版。
+func (ctx *flowContext) floatIndents(tb TextBox) ([]lineIndent, bool) {
+	if len(ctx.floats) == 0 || len(tb.Lines) == 0 {
+		return nil, true
+	}
+	height := tb.Lines[0].Height
+	gap := math.Max(tb.LineHeight-height, 0)
+	if len(tb.Lines) > 1 {
+		gap = tb.Lines[1].GapBefore
+	}
+	pitch := height + gap
+	if pitch <= 0 {
+		return nil, true
+	}
+	bottom := 0.0
+	for _, f := range ctx.floats {
+		bottom = math.Max(bottom, f.bottom)
+	}
+	var indents []lineIndent
+	for y := ctx.cursorY; y < bottom; y += pitch {
+		var ind lineIndent
+		for _, f := range ctx.floats {
+			if y >= f.bottom || y+height <= f.top {
+				continue
+			}
+			if f.right {
+				ind.right = math.Max(ind.right, f.width)
+			} else {
+				ind.left = math.Max(ind.left, f.width)
+			}
+		}
+		if ctx.width-ind.left-ind.right < 2*tb.FontSize {
+			return nil, false
+		}
+		indents = append(indents, ind)
+	}
+	return indents, true
+}
@@ -0,0 +1,167 @@
+package layout
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// wordTypesetter 把每个词视为 10mm 宽，按各行的可用宽度贪心折行。
+type wordTypesetter struct{}
+
+func (wordTypesetter) LayoutLines(content string, widths []float64, font FontResource, fontSize float64, lineHeight float64, wrap string) ([]TextLine, error) {
+	var out []TextLine
+	var words []string
+	for _, w := range strings.Fields(content) {
+		if len(words) > 0 && float64(len(words)+1)*10 > LineWidth(widths, len(out)) {
+			out = append(out, TextLine{Content: strings.Join(words, " "), Width: float64(len(words)) * 10, Height: fontSize, GapBefore: lineHeight - fontSize})
+			words = nil
+		}
+		words = append(words, w)
+	}
+	out = append(out, TextLine{Content: strings.Join(words, " "), Width: float64(len(words)) * 10, Height: fontSize, GapBefore: lineHeight - fontSize})
+	return out, nil
+}
+
+// TestImageFloatWrap 验证浮动图片不推进光标，与其重叠的文本行让出图片宽度加 gap，块级容器从浮动区域下方开始。
+func TestImageFloatWrap(t *testing.T) {
+	dslText := `doc T v1 {
+  resources {
+    font Body { src: "x.ttf" }
+    style Body { font: Body; size: 10mm }
+    image Photo { src: "photo.png" }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      image Photo width 60mm height 30mm float left
+      text Body { "` + strings.Repeat("w ", 40) + `" }
+      image Photo width 50mm height 20mm float right
+      flow { text Body { "after" } }
+    }
+  }
+}`
+	doc, err := dsl.Parse(strings.NewReader(dslText))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	res, err := Build(doc, nil, BuildOptions{Typesetter: wordTypesetter{}})
+	if err != nil {
+		t.Fatalf("布局计算失败: %v", err)
+	}
+	page := res.Pages[0]
+	if len(page.Images) != 2 {
+		t.Fatalf("期望 2 张图片，got %d", len(page.Images))
+	}
+	if img := page.Images[0]; !eq(img.X, 10) || !eq(img.Y, 10) {
+		t.Fatalf("左浮动图片位置不符: %+v", img)
+	}
+
+	// 行距 14mm：前三行（y=10/24/38）与浮动区域（10～43）重叠，左侧让出 63mm，只能放下 12 个词
+	tb := page.Texts[0]
+	if !eq(tb.Y, 10) || len(tb.Lines) != 4 {
+		t.Fatalf("绕排后的文本不符: y=%.2f lines=%d", tb.Y, len(tb.Lines))
+	}
+	for i, l := range tb.Lines {
+		indent, words := 63.0, 12
+		if i == 3 {
+			indent, words = 0, 4
+		}
+		if !eq(l.Indent, indent) || l.RightIndent != 0 || len(strings.Fields(l.Content)) != words {
+			t.Fatalf("第 %d 行不符: indent=%.2f words=%d", i+1, l.Indent, len(strings.Fields(l.Content)))
+		}
+	}
+
+	// 右浮动图片从文本下方（含块间距）开始，靠右放置；之后的 flow 从其下方开始
+	if img := page.Images[1]; !eq(img.X, 150) || !eq(img.Y, 65) {
+		t.Fatalf("右浮动图片位置不符: %+v", img)
+	}
+	if after := page.Texts[1]; !eq(after.Y, 88) {
+		t.Fatalf("flow 应从浮动区域下方开始: y=%.2f", after.Y)
+	}
+}
+
+// TestImageFloatPageBreak 验证同侧叠放的浮动图片放不下时换页，而位于页顶部的超高图片不会产生空白页。
+func TestImageFloatPageBreak(t *testing.T) {
+	dslText := `doc T v1 {
+  resources {
+    image Photo { src: "photo.png" }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      image Photo width 40mm height 300mm float left
+      image Photo width 40mm height 150mm float right
+      image Photo width 40mm height 150mm float right
+    }
+  }
+}`
+	doc, err := dsl.Parse(strings.NewReader(dslText))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	res, err := Build(doc, nil, BuildOptions{Typesetter: wordTypesetter{}})
+	if err != nil {
+		t.Fatalf("布局计算失败: %v", err)
+	}
+	if len(res.Pages) != 2 {
+		t.Fatalf("期望 2 页，got %d", len(res.Pages))
+	}
+	if imgs := res.Pages[0].Images; len(imgs) != 2 || !eq(imgs[0].Y, 10) || !eq(imgs[1].Y, 10) {
+		t.Fatalf("超高的浮动图片与第一张右浮动图片应位于第一页顶部: %+v", imgs)
+	}
+	if imgs := res.Pages[1].Images; len(imgs) != 1 || !eq(imgs[0].Y, 10) {
+		t.Fatalf("叠放放不下的右浮动图片应换到下一页顶部: %+v", imgs)
+	}
+}
+
+// TestImageFloatWrapAcrossPages 验证绕排中的段落跨页时，移到下一页的行不再让出浮动图片的宽度。
+func TestImageFloatWrapAcrossPages(t *testing.T) {
+	dslText := `doc T v1 {
+  resources {
+    font Body { src: "x.ttf" }
+    style Body { font: Body; size: 10mm }
+    image Photo { src: "photo.png" }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      image Photo width 60mm height 240mm
+      image Photo width 60mm height 30mm float left
+      text Body { "` + strings.Repeat("w ", 100) + `" }
+    }
+  }
+}`
+	doc, err := dsl.Parse(strings.NewReader(dslText))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	res, err := Build(doc, nil, BuildOptions{Typesetter: wordTypesetter{}})
+	if err != nil {
+		t.Fatalf("布局计算失败: %v", err)
+	}
+	if len(res.Pages) != 2 || len(res.Pages[0].Texts) != 1 || len(res.Pages[1].Texts) != 1 {
+		t.Fatalf("段落应拆分到两页: %+v", res.Pages)
+	}
+	words := 0
+	for _, l := range res.Pages[0].Texts[0].Lines {
+		if !eq(l.Indent, 63) {
+			t.Fatalf("第一页的行应让出浮动图片: indent=%.2f", l.Indent)
+		}
+		words += len(strings.Fields(l.Content))
+	}
+	tail := res.Pages[1].Texts[0]
+	if !eq(tail.Y, 10) {
+		t.Fatalf("续页文本位置不符: y=%.2f", tail.Y)
+	}
+	for i, l := range tail.Lines {
+		if l.Indent != 0 || l.RightIndent != 0 {
+			t.Fatalf("第二页第 %d 行不应让出宽度: indent=%.2f", i+1, l.Indent)
+		}
+		if n := len(strings.Fields(l.Content)); i < len(tail.Lines)-1 && n != 19 {
+			t.Fatalf("第二页第 %d 行应按整宽放下 19 个词，实际 %d", i+1, n)
+		}
+		words += len(strings.Fields(l.Content))
+	}
+	if words != 100 {
+		t.Fatalf("拆分后的词数应为 100，实际 %d", words)
+	}
+}
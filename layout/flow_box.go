@@ -0,0 +1,131 @@
+package layout
+
+import (
+	"strings"
+)
+
+// flow 的盒模型：padding、border、border-radius、background 与 min-height。
+// 背景与边框在 flow 排版完成后按最终内容高度生成矩形；flow 跨页（或在分栏中换栏）时每页每栏生成一段，
+// 续段从页面（栏）顶部开始，最后一段在内容底部加上下内边距处结束。
+
+// flowBox 是 flow 的外观。
+type flowBox struct {
+	padding    Margin
+	border     Border
+	radius     float64
+	background *Color
+	minHeight  float64 // 含内边距与边框的最小高度
+}
+
+// parseFlowBox 读取 flow 的外观属性：padding（1～4 个值）与 padding-*、border（宽度与颜色）、
+// border-width、border-color、border-radius、background 与 min-height。
+func parseFlowBox(attrs map[string]string, res ResourceSet) flowBox {
+	var box flowBox
+	if v := attrs["padding"]; v != "" {
+		box.padding = parsePadding(v, box.padding)
+	}
+	paddings := []*float64{&box.padding.Top, &box.padding.Right, &box.padding.Bottom, &box.padding.Left}
+	for i, name := range []string{"top", "right", "bottom", "left"} {
+		if v := attrs["padding-"+name]; v != "" {
+			*paddings[i] = parseLength(v)
+		}
+	}
+	if v := attrs["border"]; v != "" {
+		// 只写颜色时使用与表格相同的默认线宽
+		box.border = parseBorder(v, Border{Width: defaultTableBorderWidth}, res)
+	}
+	if v := attrs["border-width"]; v != "" {
+		box.border.Width = parseLength(v)
+	}
+	if v := attrs["border-color"]; v != "" {
+		box.border.Color = resolveColor(v, res)
+		if box.border.Width == 0 && attrs["border-width"] == "" {
+			box.border.Width = defaultTableBorderWidth
+		}
+	}
+	if v := attrs["border-radius"]; v != "" {
+		box.radius = parseLength(v)
+	}
+	if v := strings.TrimSpace(attrs["background"]); v != "" && v != "none" {
+		c := resolveColor(v, res)
+		box.background = &c
+	}
+	box.minHeight = parseLength(attrs["min-height"])
+	return box
+}
+
+// empty 判断 flow 是否未声明任何外观，此时 flow 的外边距与子块折叠。
+func (b flowBox) empty() bool {
+	return b.background == nil && b.border.Width <= 0 && b.padding == (Margin{}) && b.minHeight <= 0
+}
+
+// insets 返回内边距与边框在四个方向上占用的距离。
+func (b flowBox) insets() Margin {
+	w := b.border.Width
+	return Margin{Top: b.padding.Top + w, Right: b.padding.Right + w, Bottom: b.padding.Bottom + w, Left: b.padding.Left + w}
+}
+
+// flowSegment 是 flow 背景在某一页（或某一栏）中的一段。
+type flowSegment struct {
+	page   int
+	x, top float64
+	bottom float64
+	counts [6]int // 段开始时该页各类元素的数量，背景矩形插入在已有矩形之后
+}
+
+// boxTracker 记录带外观的 flow 在换页、换栏时形成的各段，由 flowContext.pageBreak 维护。
+type boxTracker struct {
+	inset    float64 // 左侧内边距与边框宽度，用于由内容横坐标得到背景横坐标
+	segments []flowSegment
+	open     flowSegment
+}
+
+// segmentAt 返回从 (x, top) 开始的一段。
+func segmentAt(ctx *flowContext, x, top float64) flowSegment {
+	return flowSegment{page: ctx.collector.current, x: x, top: top, counts: ctx.collector.curr().size()}
+}
+
+// close 在换页（换栏）之前结束当前段；该段中没有放置任何内容时（flow 一开始就换页）丢弃。
+func (t *boxTracker) close(ctx *flowContext) {
+	seg := t.open
+	seg.bottom = ctx.contentBottom()
+	if ctx.collector.accs[seg.page].size() != seg.counts {
+		t.segments = append(t.segments, seg)
+	}
+}
+
+// reopen 在换页（换栏）之后从新位置开始下一段。
+func (t *boxTracker) reopen(ctx *flowContext) {
+	t.open = segmentAt(ctx, ctx.baseX-t.inset, ctx.cursorY)
+}
+
+// emit 以 bottom 结束最后一段，并为每段生成背景矩形。
+// 按从后往前的顺序插入，使同一页上先插入的矩形不影响之前记录的插入位置；背景位于 flow 内部的形状下方。
+func (t *boxTracker) emit(pc *pageCollector, box flowBox, width, bottom float64) {
+	if box.background == nil && box.border.Width <= 0 {
+		return
+	}
+	last := t.open
+	last.bottom = bottom
+	segments := append(append([]flowSegment(nil), t.segments...), last)
+	for i := len(segments) - 1; i >= 0; i-- {
+		seg := segments[i]
+		if seg.bottom <= seg.top {
+			continue
+		}
+		rc := Rect{
+			X:           seg.x,
+			Y:           seg.top,
+			Width:       width,
+			Height:      seg.bottom - seg.top,
+			StrokeColor: box.border.Color,
+			StrokeWidth: box.border.Width,
+			NoStroke:    box.border.Width <= 0,
+			FillColor:   box.background,
+			Radius:      box.radius,
+		}
+		acc := pc.accs[seg.page]
+		at := seg.counts[4]
+		acc.rects = append(acc.rects[:at], append([]Rect{rc}, acc.rects[at:]...)...)
+	}
+}
@@ -0,0 +1,71 @@
+package layout
+
+import (
+	"testing"
+)
+
+// TestFlowBox 验证 flow 的内边距、边框与背景：内容按内边距内缩，背景矩形按最终高度生成，跨页时每页一段。
+func TestFlowBox(t *testing.T) {
+	dslText := `doc T v1 {
+  resources {
+    font Body { src: "x.ttf" }
+    style Body { font: Body; size: 10pt }
+    style Big { font: Body; size: 100mm }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      text Body { "before" }
+      flow padding "2mm 4mm" border "0.5mm #333" border-radius 1mm background #eee {
+        text Body { "inside" }
+      }
+      text Body { "after" }
+      flow background #eee padding 2mm {
+        text Big { "A" }
+        text Big { "B" }
+        text Big { "C" }
+      }
+    }
+  }
+}`
+	res := buildWithData(t, dslText, nil)
+	if len(res.Pages) != 2 {
+		t.Fatalf("期望 2 页，got %d", len(res.Pages))
+	}
+	h := parseLength("10pt")
+	p1 := res.Pages[0]
+	byContent := map[string]TextBox{}
+	for _, tb := range p1.Texts {
+		byContent[tb.Content] = tb
+	}
+	top := 10 + h + 3
+	inside := byContent["inside"]
+	if !eq(inside.X, 10+4.5) || !eq(inside.Y, top+2.5) || !eq(inside.Width, 190-9) {
+		t.Fatalf("flow 内容未按内边距与边框内缩: x=%.2f y=%.2f w=%.2f", inside.X, inside.Y, inside.Width)
+	}
+	if len(p1.Rects) != 2 {
+		t.Fatalf("第 1 页期望 2 个背景矩形，got %d", len(p1.Rects))
+	}
+	box := p1.Rects[0]
+	if !eq(box.X, 10) || !eq(box.Y, top) || !eq(box.Width, 190) || !eq(box.Height, h+5) {
+		t.Fatalf("背景矩形尺寸不符: %+v", box)
+	}
+	if box.FillColor == nil || *box.FillColor != (Color{R: 238, G: 238, B: 238}) || box.Radius != 1 || box.NoStroke || !eq(box.StrokeWidth, 0.5) {
+		t.Fatalf("背景矩形外观不符: %+v", box)
+	}
+	if after := byContent["after"]; !eq(after.Y, top+h+5+3) {
+		t.Fatalf("flow 之后的内容位置不符: got %.2f", after.Y)
+	}
+
+	// 跨页的 flow：第 1 页一段延伸到内容区域底部，第 2 页一段从顶部开始到内容结束处
+	first := p1.Rects[1]
+	if !eq(first.Y+first.Height, 287) || !first.NoStroke {
+		t.Fatalf("跨页 flow 第 1 段不符: %+v", first)
+	}
+	p2 := res.Pages[1]
+	if len(p2.Rects) != 1 || len(p2.Texts) != 1 {
+		t.Fatalf("第 2 页内容不符: rects=%d texts=%d", len(p2.Rects), len(p2.Texts))
+	}
+	if seg := p2.Rects[0]; !eq(seg.Y, 10) || !eq(seg.Y+seg.Height, p2.Texts[0].Y+100+2) {
+		t.Fatalf("跨页 flow 第 2 段不符: %+v", seg)
+	}
+}
@@ -0,0 +1,219 @@
+package layout
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// grid 容器：`grid columns 3 gap 6mm { cell { ... } }` 按列轨道从左到右、自上而下放置 cell，
+// cell 内容按不分页的 flow 排版（与富内容单元格相同）；行高取该行最高的 cell（不低于 row-height），
+// 行与行之间可以分页，单行不拆分。
+
+// gridTrack 是一条列轨道：fr 为按比例分配剩余宽度，% 为容器宽度的百分比，否则为固定长度（mm）。
+type gridTrack struct {
+	value float64
+	unit  string // ""、"%" 或 "fr"
+}
+
+// gridCell 是展开控制语句后的一个 cell。
+type gridCell struct {
+	block *dsl.Block
+	data  any
+	span  int
+}
+
+// gridSlot 是 cell 在某一行中的位置。
+type gridSlot struct {
+	cell *gridCell
+	col  int
+	span int
+}
+
+// parseGridTracks 解析 columns：整数 n 表示 n 条等宽轨道，否则为空格分隔的轨道列表，如 "40mm 1fr 2fr 25%"。
+func parseGridTracks(value string) ([]gridTrack, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return []gridTrack{{value: 1, unit: "fr"}}, nil
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		if n <= 0 {
+			return nil, fmt.Errorf("grid columns 必须大于 0：%s", value)
+		}
+		tracks := make([]gridTrack, n)
+		for i := range tracks {
+			tracks[i] = gridTrack{value: 1, unit: "fr"}
+		}
+		return tracks, nil
+	}
+	var tracks []gridTrack
+	for _, field := range strings.Fields(value) {
+		switch {
+		case field == "auto":
+			// 与表格不同，grid 轨道不按内容测量宽度
+			return nil, fmt.Errorf("grid 列轨道不支持 auto，请使用固定长度、百分比或 fr：%s", value)
+		case field == "fr":
+			tracks = append(tracks, gridTrack{value: 1, unit: "fr"})
+		case strings.HasSuffix(field, "fr"):
+			f, err := strconv.ParseFloat(strings.TrimSuffix(field, "fr"), 64)
+			if err != nil || f < 0 {
+				return nil, fmt.Errorf("grid 列轨道无效：%s", field)
+			}
+			tracks = append(tracks, gridTrack{value: f, unit: "fr"})
+		case strings.HasSuffix(field, "%"):
+			f, err := strconv.ParseFloat(strings.TrimSuffix(field, "%"), 64)
+			if err != nil || f < 0 {
+				return nil, fmt.Errorf("grid 列轨道无效：%s", field)
+			}
+			tracks = append(tracks, gridTrack{value: f, unit: "%"})
+		case isLength(field):
+			tracks = append(tracks, gridTrack{value: parseLength(field)})
+		default:
+			return nil, fmt.Errorf("grid 列轨道无效：%s", field)
+		}
+	}
+	return tracks, nil
+}
+
+// resolveGridTracks 计算各列宽度：先扣除列间距、固定与百分比轨道，剩余宽度按 fr 比例分配（不足时 fr 轨道宽度为 0）。
+func resolveGridTracks(tracks []gridTrack, width, gap float64) []float64 {
+	widths := make([]float64, len(tracks))
+	remaining := width - gap*float64(len(tracks)-1)
+	totalFr := 0.0
+	for i, t := range tracks {
+		switch t.unit {
+		case "fr":
+			totalFr += t.value
+			continue
+		case "%":
+			widths[i] = width * t.value / 100
+		default:
+			widths[i] = t.value
+		}
+		remaining -= widths[i]
+	}
+	if totalFr > 0 && remaining > 0 {
+		for i, t := range tracks {
+			if t.unit == "fr" {
+				widths[i] = remaining * t.value / totalFr
+			}
+		}
+	}
+	return widths
+}
+
+// collectGridCells 展开控制语句，收集 grid 中的 cell 及其作用域。
+func collectGridCells(block *dsl.Block, data any) ([]*gridCell, error) {
+	var cells []*gridCell
+	err := walkStatements(block, data, func(stmt *dsl.Statement, data any) error {
+		if stmt.Command == nil {
+			return nil
+		}
+		if stmt.Command.Name != "cell" {
+			return fmt.Errorf("grid 中只能包含 cell，遇到 %s", stmt.Command.Name)
+		}
+		_, attrs := parseArgs(stmt.Command.Args, false)
+		span := 1
+		if v := attrs["span"]; v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("cell span 必须为正整数：%s", v)
+			}
+			span = n
+		}
+		cells = append(cells, &gridCell{block: stmt.Command.Block, data: data, span: span})
+		return nil
+	})
+	return cells, err
+}
+
+// placeGridCells 按顺序将 cell 放入各行：当前行剩余列数不足时换到下一行，span 超过列数时按列数计。
+func placeGridCells(cells []*gridCell, columns int) [][]gridSlot {
+	var rows [][]gridSlot
+	var row []gridSlot
+	col := 0
+	for _, cell := range cells {
+		span := minInt(cell.span, columns)
+		if col+span > columns {
+			rows = append(rows, row)
+			row, col = nil, 0
+		}
+		row = append(row, gridSlot{cell: cell, col: col, span: span})
+		col += span
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func handleGrid(cmd *dsl.Command, ctx *flowContext, res ResourceSet) error {
+	if cmd.Block == nil {
+		return fmt.Errorf("grid 语句缺少内容")
+	}
+	styleName, attrs := parseArgs(cmd.Args, false)
+	attrs = mergeStyleAttributes(styleName, attrs, res.Styles)
+	tracks, err := parseGridTracks(attrs["columns"])
+	if err != nil {
+		return err
+	}
+	gap := math.Max(parseLength(attrs["gap"]), 0)
+	rowGap := gap
+	if v := attrs["row-gap"]; v != "" {
+		rowGap = math.Max(parseLength(v), 0)
+	}
+	rowHeight := parseLength(attrs["row-height"])
+	widths := resolveGridTracks(tracks, ctx.width, gap)
+	offsets := make([]float64, len(widths))
+	for i := 1; i < len(widths); i++ {
+		offsets[i] = offsets[i-1] + widths[i-1] + gap
+	}
+
+	cells, err := collectGridCells(cmd.Block, ctx.data)
+	if err != nil {
+		return err
+	}
+	before, after := blockMargins(attrs, res)
+	ctx.beginBlock(before)
+	for r, row := range placeGridCells(cells, len(widths)) {
+		// 先在 y=0 处排版整行，得到行高后再放到页面上
+		contents := make([]*CellContent, 0, len(row))
+		height := rowHeight
+		layoutX := ctx.baseX
+		for _, slot := range row {
+			last := slot.col + slot.span - 1
+			width := offsets[last] + widths[last] - offsets[slot.col]
+			content, h, err := layoutCellContent(slot.cell.block, slot.cell.data, layoutX+offsets[slot.col], 0, width, res, ctx.typesetter, ctx.debug)
+			if err != nil {
+				return err
+			}
+			contents = append(contents, content)
+			height = math.Max(height, h)
+		}
+		if r > 0 {
+			ctx.cursorY += rowGap
+		}
+		ctx.ensureSpace(height)
+		if acc := ctx.acc(); acc != nil {
+			for _, content := range contents {
+				acc.appendContent(shiftCellContent(content, ctx.baseX-layoutX, ctx.cursorY))
+			}
+		}
+		ctx.cursorY += height
+	}
+	ctx.endBlock(after)
+	return nil
+}
+
+// appendContent 将单元格内容中的各类元素追加到页面。
+func (p *pageAccumulator) appendContent(c *CellContent) {
+	p.texts = append(p.texts, c.Texts...)
+	p.images = append(p.images, c.Images...)
+	p.tables = append(p.tables, c.Tables...)
+	p.lines = append(p.lines, c.Lines...)
+	p.rects = append(p.rects, c.Rects...)
+	p.circles = append(p.circles, c.Circles...)
+}
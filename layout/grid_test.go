@@ -0,0 +1,93 @@
+package layout
+
+import (
+	"testing"
+)
+
+// TestGridLayout 验证 grid 的列轨道、gap/row-gap、row-height、span，以及行之间的分页。
+func TestGridLayout(t *testing.T) {
+	tracks, err := parseGridTracks("40mm 1fr 2fr 25%")
+	if err != nil {
+		t.Fatalf("解析列轨道失败: %v", err)
+	}
+	widths := resolveGridTracks(tracks, 200, 5)
+	for i, want := range []float64{40, 95.0 / 3, 190.0 / 3, 50} {
+		if !eq(widths[i], want) {
+			t.Fatalf("第 %d 列宽度不符: got %.2f, want %.2f", i, widths[i], want)
+		}
+	}
+	if _, err := parseGridTracks("1fr abc"); err == nil {
+		t.Fatalf("期望无效列轨道报错")
+	}
+	if _, err := parseGridTracks("40mm auto"); err == nil {
+		t.Fatalf("期望 auto 列轨道报错")
+	}
+
+	dslText := `doc T v1 {
+  resources {
+    font Body { src: "x.ttf" }
+    style Body { font: Body; size: 10pt }
+    style Big { font: Body; size: 100mm }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      grid columns 3 gap 6mm row-gap 4mm row-height 20mm {
+        for item in data.metrics {
+          cell { text Body { "${item}" } }
+        }
+        cell span 2 {
+          rect x 0 y 0 width 5mm height 5mm fill #eee
+          text Body { "wide" }
+        }
+      }
+      grid columns 1 {
+        cell { text Big { "R1" } }
+        cell { text Big { "R2" } }
+        cell { text Big { "R3" } }
+      }
+    }
+  }
+}`
+	res := buildWithData(t, dslText, map[string]any{"metrics": []any{"a", "b", "c", "d", "e"}})
+	if len(res.Pages) != 2 {
+		t.Fatalf("期望 2 页，got %d", len(res.Pages))
+	}
+	col := (190 - 12) / 3.0
+	want := map[string][3]float64{ // x, y, width
+		"a":    {10, 10, col},
+		"c":    {10 + 2*(col+6), 10, col},
+		"d":    {10, 34, col},
+		"e":    {10 + col + 6, 34, col},
+		"wide": {10, 58, 2*col + 6},
+		"R1":   {10, 81, 190},
+		"R2":   {10, 181, 190},
+	}
+	texts := map[string]TextBox{}
+	for _, tb := range res.Pages[0].Texts {
+		texts[tb.Content] = tb
+	}
+	for name, w := range want {
+		tb, ok := texts[name]
+		if !ok || !eq(tb.X, w[0]) || !eq(tb.Y, w[1]) || !eq(tb.Width, w[2]) {
+			t.Fatalf("%s 的位置不符: got (%.2f, %.2f, %.2f), want %v", name, tb.X, tb.Y, tb.Width, w)
+		}
+	}
+	if rects := res.Pages[0].Rects; len(rects) != 1 || !eq(rects[0].X, 10) || !eq(rects[0].Y, 58) {
+		t.Fatalf("cell 中的形状应相对于 cell 左上角: %+v", rects)
+	}
+	if p2 := res.Pages[1].Texts; len(p2) != 1 || p2[0].Content != "R3" || !eq(p2[0].Y, 10) {
+		t.Fatalf("grid 行应整体移到下一页: %+v", p2)
+	}
+}
+
+// TestPlaceGridCellsClampsSpan 验证 span 超过列数时按列数计，放不下的单元格换到下一行。
+func TestPlaceGridCellsClampsSpan(t *testing.T) {
+	cells := []*gridCell{{span: 5}, {span: 1}, {span: 2}}
+	rows := placeGridCells(cells, 3)
+	if len(rows) != 2 || len(rows[0]) != 1 || rows[0][0].span != 3 {
+		t.Fatalf("span 5 应截断为 3 列并独占一行: %+v", rows)
+	}
+	if got := rows[1]; len(got) != 2 || got[0].col != 0 || got[1].col != 1 || got[1].span != 2 {
+		t.Fatalf("第二行位置不符: %+v", got)
+	}
+}
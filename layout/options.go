@@ -1,9 +1,20 @@
 package layout
 
+import (
+	"github.com/ByLCY/papyrus/binding"
+	"github.com/ByLCY/papyrus/schema"
+)
+
 // BuildOptions 配置布局阶段所需的依赖，例如排版后端。
 type BuildOptions struct {
 	Typesetter Typesetter
 	Debug      DebugOptions
+	Funcs      binding.FuncMap // 表达式中可调用的自定义函数，与内置函数同名时覆盖内置函数
+	// MissingBinding 决定 ${...} 引用的路径不存在时的处理方式：保留占位符（默认）、输出空字符串或使构建失败。
+	// 无论哪种策略，未解析的绑定都会收集到 Result.Unresolved；MissingError 时 Build 返回 *binding.UnresolvedError。
+	MissingBinding binding.MissingPolicy
+	// Schema 非空时，Build 先按其校验数据，不符合时返回 *schema.ValidationError（错误带 JSON Pointer 路径）。
+	Schema *schema.Schema
 }
 
 // DebugOptions 控制调试相关输出。
@@ -12,6 +23,18 @@ type DebugOptions struct {
 }
 
 // Typesetter 负责根据字体与宽度约束将文本拆成可绘制的行。
+// widths[i] 为第 i 行的可用宽度（文字绕排浮动图片时各行不同），行数超过 len(widths) 时沿用最后一个值，见 LineWidth。
 type Typesetter interface {
-	LayoutLines(content string, width float64, font FontResource, fontSize float64, lineHeight float64, wrap string) ([]TextLine, error)
+	LayoutLines(content string, widths []float64, font FontResource, fontSize float64, lineHeight float64, wrap string) ([]TextLine, error)
+}
+
+// LineWidth 返回第 i 行的可用宽度：超出 widths 长度时取最后一个值，widths 为空时返回 0（不限宽度）。
+func LineWidth(widths []float64, i int) float64 {
+	if len(widths) == 0 {
+		return 0
+	}
+	if i >= len(widths) {
+		i = len(widths) - 1
+	}
+	return widths[i]
 }
@@ -0,0 +1,178 @@
+package layout
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// 该文件负责 page-set 模板：收集模板定义，并与 `page use <name> ...` 的页面级覆盖合并。
+
+// pageTemplate 记录 page 段落与其引用的 page-set 合并后的有效定义。
+type pageTemplate struct {
+	spec       dsl.PageSpec   // 合并后的尺寸与头部参数（方向、边距）
+	header     *dsl.Command   // 页面级 header 优先，否则取模板
+	footer     *dsl.Command   // 页面级 footer 优先，否则取模板
+	background []*dsl.Command // 模板中的形状，会在该段落的每一页重复绘制
+	styles     []*dsl.Command // 模板与页面中的 style 定义（页面定义在后，覆盖模板）
+}
+
+// collectPageSets 收集文档中的所有 page-set 定义。
+func collectPageSets(doc *dsl.Document) (map[string]*dsl.PageSetSection, error) {
+	sets := map[string]*dsl.PageSetSection{}
+	for _, section := range doc.Sections {
+		if section.PageSet == nil {
+			continue
+		}
+		name := section.PageSet.Name
+		if _, ok := sets[name]; ok {
+			return nil, fmt.Errorf("page-set %s 重复定义", name)
+		}
+		sets[name] = section.PageSet
+	}
+	return sets, nil
+}
+
+// resolvePageTemplate 计算 page 段落的有效定义。
+// 普通页面（如 `page A4 portrait margin 18mm`）直接使用自身定义；
+// `page use Letterhead [A5] [landscape] [margin 10mm]` 先套用模板，再叠加页面头部参数与 block 内的 header/footer/style。
+func resolvePageTemplate(section *dsl.PageSection, sets map[string]*dsl.PageSetSection) (pageTemplate, error) {
+	var tpl pageTemplate
+	params := section.Spec.Params
+	tpl.spec = section.Spec
+
+	if strings.EqualFold(section.Spec.Size, "use") {
+		if len(params) == 0 {
+			return tpl, fmt.Errorf("page use 缺少 page-set 名称")
+		}
+		name := params[0].Value
+		set, ok := sets[name]
+		if !ok {
+			return tpl, fmt.Errorf("page-set %s 未定义", name)
+		}
+		base := pageSetSpec(set)
+		rest := params[1:]
+		size := base.Size
+		if len(rest) > 0 {
+			if _, ok := pagePresets[strings.ToUpper(rest[0].Value)]; ok {
+				size = rest[0].Value
+				rest = rest[1:]
+			}
+		}
+		if size == "" {
+			size = "A4"
+		}
+		merged := make([]*dsl.Lexeme, 0, len(base.Params)+len(rest))
+		merged = append(merged, base.Params...)
+		merged = append(merged, rest...)
+		tpl.spec = dsl.PageSpec{Size: size, Params: merged}
+
+		if set.Block != nil {
+			for _, st := range set.Block.Statements {
+				if st.Command == nil {
+					continue
+				}
+				switch strings.ToLower(st.Command.Name) {
+				case "header":
+					tpl.header = st.Command
+				case "footer":
+					tpl.footer = st.Command
+				case "line", "rect", "circle":
+					tpl.background = append(tpl.background, st.Command)
+				case "style":
+					tpl.styles = append(tpl.styles, st.Command)
+				}
+			}
+		}
+	}
+
+	if section.Block != nil {
+		for _, st := range section.Block.Statements {
+			if st.Command == nil {
+				continue
+			}
+			switch st.Command.Name {
+			case "header":
+				tpl.header = st.Command
+			case "footer":
+				tpl.footer = st.Command
+			case "style":
+				tpl.styles = append(tpl.styles, st.Command)
+			}
+		}
+	}
+	return tpl, nil
+}
+
+// pageSetSpec 将 page-set 中的 size/orientation/margin 赋值转换为等价的页面头部参数。
+// margin 可写为单值（18mm）、数组（[18mm, 12mm]）或字符串（"18mm 12mm"）。
+func pageSetSpec(set *dsl.PageSetSection) dsl.PageSpec {
+	var spec dsl.PageSpec
+	if set.Block == nil {
+		return spec
+	}
+	for _, st := range set.Block.Statements {
+		if st.Assignment == nil {
+			continue
+		}
+		switch strings.ToLower(st.Assignment.Key) {
+		case "size":
+			spec.Size = valueToString(st.Assignment.Value)
+		case "orientation":
+			if v := valueToString(st.Assignment.Value); v != "" {
+				spec.Params = append(spec.Params, &dsl.Lexeme{Type: "Ident", Value: v, Raw: v})
+			}
+		case "margin":
+			var values []string
+			for _, v := range valueToStringSlice(st.Assignment.Value) {
+				values = append(values, strings.Fields(v)...)
+			}
+			if len(values) == 0 {
+				continue
+			}
+			spec.Params = append(spec.Params, &dsl.Lexeme{Type: "Ident", Value: "margin", Raw: "margin"})
+			for _, v := range values {
+				spec.Params = append(spec.Params, &dsl.Lexeme{Type: "Number", Value: v, Raw: v})
+			}
+		}
+	}
+	return spec
+}
+
+// scopedStyles 在全局样式的基础上叠加页面级 style 定义。
+// 同名样式按属性合并（仅覆盖声明的属性）；声明 extends 时以被继承样式为基础。
+func scopedStyles(global map[string]Style, cmds []*dsl.Command) (map[string]Style, error) {
+	if len(cmds) == 0 {
+		return global, nil
+	}
+	out := make(map[string]Style, len(global)+len(cmds))
+	for k, v := range global {
+		out[k] = v
+	}
+	for _, cmd := range cmds {
+		style := parseStyleResource(cmd)
+		if style.Name == "" {
+			continue
+		}
+		props := map[string]string{}
+		baseName := style.Name
+		if style.Extends != "" {
+			baseName = style.Extends
+			if _, ok := out[baseName]; !ok {
+				return nil, fmt.Errorf("style %s 未定义", baseName)
+			}
+		}
+		if base, ok := out[baseName]; ok {
+			for k, v := range base.Props {
+				props[k] = v
+			}
+		}
+		for k, v := range style.Props {
+			props[k] = v
+		}
+		style.Props = props
+		out[style.Name] = style
+	}
+	return out, nil
+}
@@ -0,0 +1,235 @@
+package layout
+
+import (
+	"strings"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// 分页控制：`pagebreak` 命令，以及 flow/text/image/table/grid/row/columns 上的 break-before、break-after、keep-together 与 keep-with-next。
+// keep-* 通过检查点实现：记录块开始时的排版进度，违反约束时回退到检查点，换页后重新排版。
+
+// checkpoint 记录排版进度：当前页、页数、当前页各类元素的数量，以及上下文链的坐标。
+type checkpoint struct {
+	page   int
+	pages  int
+	counts [6]int
+	ctxs   []ctxState
+	atTop  bool // 检查点位于内容区域顶部，此时换页无济于事
+}
+
+type ctxState struct {
+	ctx                               *flowContext
+	baseX, baseY, cursorY, spaceAfter float64
+	columns                           columnState
+	segments                          int
+	open                              flowSegment
+	floats                            []floatArea
+}
+
+// mark 记录当前的排版进度。pageBreak 会修改祖先上下文的坐标，因此需要保存整条上下文链。
+func (ctx *flowContext) mark() checkpoint {
+	var cp checkpoint
+	for c := ctx; c != nil; c = c.parent {
+		s := ctxState{ctx: c, baseX: c.baseX, baseY: c.baseY, cursorY: c.cursorY, spaceAfter: c.spaceAfter, floats: c.floats}
+		if c.columns != nil {
+			s.columns = *c.columns
+		}
+		if c.box != nil {
+			s.segments, s.open = len(c.box.segments), c.box.open
+		}
+		cp.ctxs = append(cp.ctxs, s)
+	}
+	if ctx.collector != nil {
+		cp.page = ctx.collector.current
+		cp.pages = len(ctx.collector.accs)
+		cp.counts = ctx.collector.curr().size()
+		cp.atTop = ctx.atTop()
+	}
+	return cp
+}
+
+// restore 回退到检查点：丢弃之后新增的页面与元素，并恢复上下文链的坐标。
+func (ctx *flowContext) restore(cp checkpoint) {
+	for _, s := range cp.ctxs {
+		s.ctx.baseX, s.ctx.baseY, s.ctx.cursorY, s.ctx.spaceAfter = s.baseX, s.baseY, s.cursorY, s.spaceAfter
+		s.ctx.floats = s.floats
+		if s.ctx.columns != nil {
+			*s.ctx.columns = s.columns
+		}
+		if s.ctx.box != nil {
+			s.ctx.box.segments, s.ctx.box.open = s.ctx.box.segments[:s.segments], s.open
+		}
+	}
+	if pc := ctx.collector; pc != nil {
+		pc.accs = pc.accs[:cp.pages]
+		pc.current = cp.page
+		pc.accs[cp.page].truncate(cp.counts)
+	}
+}
+
+// spilled 判断自检查点以来是否发生了换页（分栏中为换栏）。
+func (ctx *flowContext) spilled(cp checkpoint) bool {
+	if ctx.collector == nil {
+		return false
+	}
+	if s := cp.columnsState(ctx); s != nil && s.ctx.columns.index != s.columns.index {
+		return true
+	}
+	return ctx.collector.current != cp.page
+}
+
+// placedOnPage 判断自检查点以来是否有元素放在检查点所在的页面（分栏中为所在的栏）上。
+func (ctx *flowContext) placedOnPage(cp checkpoint) bool {
+	if ctx.collector == nil {
+		return false
+	}
+	if s := cp.columnsState(ctx); s != nil && ctx.collector.current == cp.page && s.ctx.columns.index != s.columns.index {
+		return s.ctx.columns.ends[s.columns.index] != cp.counts
+	}
+	return ctx.collector.accs[cp.page].size() != cp.counts
+}
+
+// columnsState 返回检查点中 ctx 所在的最内层分栏的状态，不在分栏中时返回 nil。
+func (cp checkpoint) columnsState(ctx *flowContext) *ctxState {
+	for c := ctx; c != nil; c = c.parent {
+		if c.columns == nil {
+			continue
+		}
+		for i := range cp.ctxs {
+			if cp.ctxs[i].ctx == c {
+				return &cp.ctxs[i]
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// explicitBreak 处理 pagebreak 与 break-before/after：已位于内容区域（栏）顶部时不再换页，避免产生空白页。
+// 在分栏中换到下一栏。
+func (ctx *flowContext) explicitBreak() {
+	if !ctx.allowPageBreak || ctx.collector == nil || ctx.atTop() {
+		return
+	}
+	ctx.pageBreak()
+}
+
+func (p *pageAccumulator) size() [6]int {
+	return [6]int{len(p.texts), len(p.images), len(p.tables), len(p.lines), len(p.rects), len(p.circles)}
+}
+
+func (p *pageAccumulator) truncate(n [6]int) {
+	p.texts = p.texts[:n[0]]
+	p.images = p.images[:n[1]]
+	p.tables = p.tables[:n[2]]
+	p.lines = p.lines[:n[3]]
+	p.rects = p.rects[:n[4]]
+	p.circles = p.circles[:n[5]]
+}
+
+// blockAttrs 按各命令自身的参数规则解析属性（含样式中的属性），用于读取分页控制属性；非块级命令返回 nil。
+func blockAttrs(cmd *dsl.Command, res ResourceSet) map[string]string {
+	var styleName string
+	var attrs map[string]string
+	switch cmd.Name {
+	case "text", "image":
+		styleName, attrs = parseArgs(cmd.Args, true)
+	case "flow", "grid", "row":
+		styleName, attrs = parseArgs(cmd.Args, false)
+	case "columns":
+		_, args := splitColumnCount(cmd.Args)
+		styleName, attrs = parseArgs(args, false)
+	case "table":
+		_, args := splitTableSource(cmd)
+		styleName, attrs = parseArgs(args, false)
+	default:
+		return nil
+	}
+	return mergeStyleAttributes(styleName, attrs, res.Styles)
+}
+
+// isBreak 判断 break-before/break-after 的取值是否要求换页：page、always 或 true。
+func isBreak(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "page", "always", "true":
+		return true
+	}
+	return false
+}
+
+// isEnabled 判断开关类属性（keep-together、keep-with-next、balance 等）是否开启：true、always 或 yes。
+func isEnabled(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "true", "always", "yes":
+		return true
+	}
+	return false
+}
+
+// keepGroup 是以 keep-with-next 连在一起、尚未确定位置的一组块：start 为第一个块之前的检查点，
+// end 为最后一个块之后的检查点，replay 按顺序重新排版这些块。
+type keepGroup struct {
+	start  checkpoint
+	end    checkpoint
+	replay []func() error
+}
+
+// layoutKept 按分页控制属性排版一个块级命令。layoutCmd 排版该命令（回退后也用它重新排版，需绑定命令原本的作用域），
+// pending 为同一 block 中尚未确定位置的 keep-with-next 块组。
+func layoutKept(ctx *flowContext, attrs map[string]string, layoutCmd func() error, pending **keepGroup) error {
+	if !ctx.allowPageBreak {
+		return layoutCmd()
+	}
+	if isBreak(attrs["break-before"]) {
+		ctx.explicitBreak()
+		*pending = nil
+	}
+
+	start := ctx.mark()
+	if err := layoutCmd(); err != nil {
+		return err
+	}
+	// keep-together：块被分到两页且开始处不在页顶时，整块移到下一页
+	if isEnabled(attrs["keep-together"]) && ctx.spilled(start) && !start.atTop {
+		ctx.restore(start)
+		ctx.pageBreak()
+		start = ctx.mark()
+		if err := layoutCmd(); err != nil {
+			return err
+		}
+	}
+	// keep-with-next：上一组块之后的内容没有一项留在同一页时，将整组移到下一页与本块一起排版
+	if g := *pending; g != nil {
+		if ctx.spilled(g.end) && !ctx.placedOnPage(g.end) && !g.start.atTop {
+			ctx.restore(g.start)
+			ctx.pageBreak()
+			for _, replay := range g.replay {
+				if err := replay(); err != nil {
+					return err
+				}
+			}
+			start = ctx.mark()
+			if err := layoutCmd(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if isEnabled(attrs["keep-with-next"]) {
+		g := *pending
+		if g == nil {
+			g = &keepGroup{start: start}
+		}
+		g.replay = append(g.replay, layoutCmd)
+		g.end = ctx.mark()
+		*pending = g
+	} else {
+		*pending = nil
+	}
+	if isBreak(attrs["break-after"]) {
+		ctx.explicitBreak()
+		*pending = nil
+	}
+	return nil
+}
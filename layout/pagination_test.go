@@ -0,0 +1,74 @@
+package layout
+
+import (
+	"reflect"
+	"testing"
+)
+
+func pagesTexts(res *Result) [][]string {
+	out := make([][]string, 0, len(res.Pages))
+	for _, p := range res.Pages {
+		out = append(out, pageTexts(p))
+	}
+	return out
+}
+
+// TestPageBreaks 验证 pagebreak 与 break-before/break-after：位于页顶时不再换页，不会产生空白页。
+func TestPageBreaks(t *testing.T) {
+	dslText := `doc T v1 {
+  resources {
+    font Body { src: "x.ttf" }
+    style Body { font: Body; size: 10pt }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      pagebreak
+      text { "A" }
+      pagebreak
+      pagebreak
+      text Body break-after page { "B" }
+      text Body break-before page { "C" }
+      flow break-before always { text { "D" } }
+      text { "E" }
+    }
+  }
+}`
+	res := buildWithData(t, dslText, nil)
+	want := [][]string{{"A"}, {"B"}, {"C"}, {"D", "E"}}
+	if got := pagesTexts(res); !reflect.DeepEqual(got, want) {
+		t.Fatalf("分页结果不符: got %v, want %v", got, want)
+	}
+	if y := res.Pages[2].Texts[0].Y; !eq(y, res.Pages[1].Texts[0].Y) {
+		t.Fatalf("换页后应从内容区域顶部开始，got %.2f", y)
+	}
+}
+
+// TestKeepTogetherAndWithNext 验证 keep-together 将放不下的 flow 整体移到下一页，keep-with-next 让标题与后续块同页。
+func TestKeepTogetherAndWithNext(t *testing.T) {
+	dslText := `doc T v1 {
+  resources {
+    font Body { src: "x.ttf" }
+    style Body { font: Body; size: 10pt }
+    style Big { font: Body; size: 100mm }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      text Big { "A" }
+      text Big { "B" }
+      flow keep-together true {
+        text Body { "C1" }
+        text Big { "C2" }
+      }
+      text Big { "X" }
+      text Body keep-with-next true { "Heading" }
+      text Big { "D" }
+      text Big { "E" }
+    }
+  }
+}`
+	res := buildWithData(t, dslText, nil)
+	want := [][]string{{"A", "B"}, {"C1", "C2", "X"}, {"Heading", "D", "E"}}
+	if got := pagesTexts(res); !reflect.DeepEqual(got, want) {
+		t.Fatalf("分页结果不符: got %v, want %v", got, want)
+	}
+}
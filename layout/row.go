@@ -0,0 +1,205 @@
+package layout
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// row 容器：`row gap 4mm align center justify space-between { ... }` 将子块从左到右排成一行。
+// 子块宽度可为固定长度或百分比（width）、按比例分配剩余宽度（grow），未声明时取内容的自然宽度；
+// 行高取最高的子块，整行不跨页。
+
+// rowItem 是 row 中的一个子块。
+type rowItem struct {
+	cmd   *dsl.Command
+	data  any
+	width float64
+	grow  float64
+	auto  bool // 未声明 width 与 grow，按内容自然宽度排版
+}
+
+// collectRowItems 展开控制语句并计算各子块声明的宽度。
+func collectRowItems(block *dsl.Block, data any, width float64, ctx *flowContext, res ResourceSet) ([]*rowItem, error) {
+	var items []*rowItem
+	err := walkStatements(block, data, func(stmt *dsl.Statement, data any) error {
+		cmd := stmt.Command
+		if cmd == nil {
+			return nil
+		}
+		if cmd.Name == "pagebreak" {
+			return fmt.Errorf("row 中不能使用 pagebreak")
+		}
+		item := &rowItem{cmd: cmd, data: data}
+		attrs := blockAttrs(cmd, res)
+		switch {
+		case attrs["width"] != "":
+			item.width = parseDimension(attrs["width"], width)
+		case attrs["grow"] != "":
+			g, err := strconv.ParseFloat(attrs["grow"], 64)
+			if err != nil || g < 0 {
+				return fmt.Errorf("row 子块的 grow 无效：%s", attrs["grow"])
+			}
+			item.grow = g
+		default:
+			single := &dsl.Block{Statements: []*dsl.Statement{stmt}}
+			if w := inferFlowWidth(single, res, width, ctx.typesetter, data); w > 0 {
+				item.width, item.auto = w, true
+			} else {
+				item.grow = 1
+			}
+		}
+		items = append(items, item)
+		return nil
+	})
+	return items, err
+}
+
+// resolveRowWidths 分配子块宽度并返回各子块相对于行左侧的偏移。
+// 有 grow 子块时剩余宽度按 grow 比例分配；否则按 justify 放置，自然宽度之和超出可用宽度时按比例压缩。
+func resolveRowWidths(items []*rowItem, width, gap float64, justify string) []float64 {
+	free := width - gap*float64(len(items)-1)
+	totalGrow, autoWidth := 0.0, 0.0
+	for _, item := range items {
+		free -= item.width
+		totalGrow += item.grow
+		if item.auto {
+			autoWidth += item.width
+		}
+	}
+	switch {
+	case totalGrow > 0:
+		for _, item := range items {
+			if item.grow > 0 {
+				item.width = math.Max(free, 0) * item.grow / totalGrow
+			}
+		}
+		free = 0
+	case free < 0 && autoWidth > 0:
+		scale := math.Max(autoWidth+free, 0) / autoWidth
+		for _, item := range items {
+			if item.auto {
+				item.width *= scale
+			}
+		}
+		free = 0
+	}
+
+	offset, spacing := 0.0, gap
+	if free > 0 {
+		switch justify {
+		case "center":
+			offset = free / 2
+		case "end", "right":
+			offset = free
+		case "space-between":
+			if len(items) > 1 {
+				spacing += free / float64(len(items)-1)
+			}
+		}
+	}
+	offsets := make([]float64, len(items))
+	for i, item := range items {
+		offsets[i] = offset
+		offset += item.width + spacing
+	}
+	return offsets
+}
+
+// withArgs 返回参数中 key 的取值被替换后的命令副本；add 为 true 且原参数中没有 key 时追加到末尾。
+func withArgs(cmd *dsl.Command, key, value string, add bool) *dsl.Command {
+	args := make([]*dsl.Lexeme, len(cmd.Args))
+	copy(args, cmd.Args)
+	found := false
+	for i := 0; i+1 < len(args); i++ {
+		if args[i].Type == "Ident" && args[i].Value == key {
+			args[i+1] = &dsl.Lexeme{Type: "Number", Value: value, Raw: value, Pos: args[i+1].Pos}
+			found = true
+			break
+		}
+	}
+	if !found && add {
+		args = append(args, &dsl.Lexeme{Type: "Ident", Value: key, Raw: key}, &dsl.Lexeme{Type: "Number", Value: value, Raw: value})
+	}
+	return &dsl.Command{Pos: cmd.Pos, Name: cmd.Name, Args: args, Block: cmd.Block}
+}
+
+func formatMm(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64) + "mm"
+}
+
+func handleRow(cmd *dsl.Command, ctx *flowContext, res ResourceSet) error {
+	if cmd.Block == nil {
+		return fmt.Errorf("row 语句缺少内容")
+	}
+	styleName, attrs := parseArgs(cmd.Args, false)
+	attrs = mergeStyleAttributes(styleName, attrs, res.Styles)
+	gap := math.Max(parseLength(attrs["gap"]), 0)
+	align := strings.ToLower(attrs["align"])
+	if align == "middle" {
+		align = "center"
+	}
+	justify := strings.ToLower(attrs["justify"])
+
+	items, err := collectRowItems(cmd.Block, ctx.data, ctx.width, ctx, res)
+	if err != nil {
+		return err
+	}
+	offsets := resolveRowWidths(items, ctx.width, gap, justify)
+
+	// 先在 y=0 处排版各子块；width 改写为分配后的宽度，百分比不会在子块中再次按比例计算
+	layoutItem := func(item *rowItem, x float64, minHeight float64) (*CellContent, float64, error) {
+		c := item.cmd
+		if blockAttrs(c, res)["width"] != "" {
+			c = withArgs(c, "width", formatMm(item.width), false)
+		}
+		if minHeight > 0 {
+			c = withArgs(c, "min-height", formatMm(minHeight), true)
+		}
+		return layoutIsolated(item.data, x, 0, item.width, ctx.typesetter, ctx.debug, func(sub *flowContext) error {
+			return layoutCommand(c, sub, res)
+		})
+	}
+	contents := make([]*CellContent, len(items))
+	heights := make([]float64, len(items))
+	height := 0.0
+	layoutX := ctx.baseX
+	for i, item := range items {
+		if contents[i], heights[i], err = layoutItem(item, layoutX+offsets[i], 0); err != nil {
+			return err
+		}
+		height = math.Max(height, heights[i])
+	}
+	// stretch：矮于行高的 flow 以 min-height 重新排版，使其背景与边框撑满整行
+	if align == "stretch" {
+		for i, item := range items {
+			if item.cmd.Name == "flow" && heights[i] < height {
+				if contents[i], heights[i], err = layoutItem(item, layoutX+offsets[i], height); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	before, after := blockMargins(attrs, res)
+	ctx.beginBlock(before)
+	ctx.ensureSpace(height)
+	if acc := ctx.acc(); acc != nil {
+		for i, content := range contents {
+			dy := 0.0
+			switch align {
+			case "center":
+				dy = (height - heights[i]) / 2
+			case "bottom":
+				dy = height - heights[i]
+			}
+			acc.appendContent(shiftCellContent(content, ctx.baseX-layoutX, ctx.cursorY+dy))
+		}
+	}
+	ctx.cursorY += height
+	ctx.endBlock(after)
+	return nil
+}
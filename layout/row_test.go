@@ -0,0 +1,73 @@
+package layout
+
+import (
+	"testing"
+)
+
+// TestRowLayout 验证 row 的固定/百分比/grow 宽度、gap、justify 与 align（含 stretch）。
+func TestRowLayout(t *testing.T) {
+	dslText := `doc T v1 {
+  resources {
+    font Body { src: "x.ttf" }
+    style Body { font: Body; size: 10pt }
+    image Logo { src: "logo.png" }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      row gap 5mm justify space-between align center {
+        image Logo width 30mm
+        flow width 50mm {
+          text Body { "addr1" }
+          text Body { "addr2" }
+        }
+      }
+      row gap 4mm align stretch {
+        flow width 25% background #eee { text Body { "a" } }
+        flow grow 1 background #eee {
+          text Body { "b" }
+          text Body { "c" }
+        }
+        flow grow 2 { text Body { "d" } }
+      }
+    }
+  }
+}`
+	res := buildWithData(t, dslText, nil)
+	page := res.Pages[0]
+	h := parseLength("10pt")
+	texts := map[string]TextBox{}
+	for _, tb := range page.Texts {
+		texts[tb.Content] = tb
+	}
+
+	if len(page.Images) != 1 || !eq(page.Images[0].X, 10) || !eq(page.Images[0].Y, 10) {
+		t.Fatalf("logo 位置不符: %+v", page.Images)
+	}
+	// 行高取 logo 高度 18mm，地址块右对齐（space-between）并垂直居中
+	addrTop := 10 + (18-(2*h+3))/2
+	if a := texts["addr1"]; !eq(a.X, 150) || !eq(a.Y, addrTop) || !eq(a.Width, 50) {
+		t.Fatalf("地址块位置不符: x=%.2f y=%.2f w=%.2f", a.X, a.Y, a.Width)
+	}
+
+	top := 10 + 18 + 3.0
+	wantX := map[string][2]float64{ // x, width
+		"a": {10, 47.5},
+		"b": {10 + 47.5 + 4, 134.5 / 3},
+		"d": {10 + 47.5 + 4 + 134.5/3 + 4, 134.5 * 2 / 3},
+	}
+	for name, w := range wantX {
+		tb := texts[name]
+		if !eq(tb.X, w[0]) || !eq(tb.Width, w[1]) || !eq(tb.Y, top) {
+			t.Fatalf("%s 的位置不符: x=%.2f y=%.2f w=%.2f, want %v", name, tb.X, tb.Y, tb.Width, w)
+		}
+	}
+	// stretch：两个背景矩形都撑满行高
+	if len(page.Rects) != 2 {
+		t.Fatalf("期望 2 个背景矩形，got %d", len(page.Rects))
+	}
+	for _, rc := range page.Rects {
+		if !eq(rc.Y, top) || !eq(rc.Height, 2*h+3) {
+			t.Fatalf("stretch 后的背景矩形不符: %+v", rc)
+		}
+	}
+}
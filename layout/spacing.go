@@ -0,0 +1,60 @@
+package layout
+
+import (
+	"math"
+	"strings"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// 块间距：text、image、table、flow 可通过 margin-top/margin-bottom（或样式中的 spacing: { before; after }）声明上下外边距。
+// 相邻块之间的间距取上一块下边距与下一块上边距中的较大值（与 CSS 的外边距折叠一致），换页后丢弃。
+// 未声明时上边距为 0，下边距为文档默认间距（resources 中的 block-spacing，缺省为 defaultBlockSpacing）。
+
+const defaultBlockSpacing = 3.0
+
+// blockMargins 返回块的上下外边距，margin-* 优先于 spacing.*。
+func blockMargins(attrs map[string]string, res ResourceSet) (before, after float64) {
+	before, after = 0, res.BlockSpacing
+	if v := firstNonEmpty(attrs["margin-top"], attrs["spacing.before"]); v != "" {
+		before = math.Max(parseLength(v), 0)
+	}
+	if v := firstNonEmpty(attrs["margin-bottom"], attrs["spacing.after"]); v != "" {
+		after = math.Max(parseLength(v), 0)
+	}
+	return before, after
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// beginBlock 在放置块之前推进光标：上边距与上一块尚未计入的下边距折叠。
+func (ctx *flowContext) beginBlock(before float64) {
+	ctx.blockStart = ctx.cursorY
+	ctx.cursorY += math.Max(ctx.spaceAfter, before)
+	ctx.blockTop, ctx.spaceAfter = ctx.cursorY, 0
+}
+
+// endBlock 记录块的下边距，待下一块开始时再与其上边距折叠。
+func (ctx *flowContext) endBlock(after float64) {
+	ctx.spaceAfter = math.Max(ctx.spaceAfter, after)
+}
+
+// flattenValue 将样式属性写入 props；行内对象（如 spacing: { before: 6pt }）展开为 spacing.before 形式的键。
+func flattenValue(key string, val *dsl.Value, props map[string]string) {
+	if val != nil && val.Object != nil {
+		for _, entry := range val.Object.Entries {
+			flattenValue(key+"."+entry.Key, entry.Value, props)
+		}
+		return
+	}
+	if s := valueToString(val); s != "" {
+		props[key] = s
+	}
+}
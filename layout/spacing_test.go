@@ -0,0 +1,73 @@
+package layout
+
+import (
+	"testing"
+)
+
+// TestBlockSpacing 验证块间距：样式中的 spacing 对象、margin-top 与 flow 的外边距按较大值折叠，默认间距由 block-spacing 指定。
+func TestBlockSpacing(t *testing.T) {
+	dslText := `doc T v1 {
+  resources {
+    block-spacing: 5mm
+    font Body { src: "x.ttf" }
+    style Body { font: Body; size: 10pt }
+    style Heading extends Body {
+      spacing: { before: 10mm; after: 2mm }
+    }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      text Body { "A" }
+      text Heading { "H" }
+      text Body { "B" }
+      text Body margin-top 8mm { "C" }
+      flow margin-top 1mm { text Body { "D" } }
+      text Body { "E" }
+    }
+  }
+}`
+	res := buildWithData(t, dslText, nil)
+	if style := res.Resources.Styles["Heading"]; style.Props["spacing.before"] != "10mm" || style.Props["spacing.after"] != "2mm" {
+		t.Fatalf("spacing 对象未展开: %v", style.Props)
+	}
+	h := parseLength("10pt")
+	want := map[string]float64{
+		"A": 10,
+		"H": 20 + h,
+		"B": 22 + 2*h,
+		"C": 30 + 3*h,
+		"D": 35 + 4*h,
+		"E": 40 + 5*h,
+	}
+	for _, tb := range res.Pages[0].Texts {
+		if !eq(tb.Y, want[tb.Content]) {
+			t.Fatalf("%s 的位置不符: got %.3f, want %.3f", tb.Content, tb.Y, want[tb.Content])
+		}
+	}
+}
+
+// TestEmptyFlowKeepsSpacing 验证没有放置内容的 flow 不会丢弃前一块的下边距。
+func TestEmptyFlowKeepsSpacing(t *testing.T) {
+	dslText := `doc T v1 {
+  resources {
+    block-spacing: 5mm
+    font Body { src: "x.ttf" }
+    style Body { font: Body; size: 10pt }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      text Body margin-bottom 20mm { "A" }
+      flow { for x in data.empty { text Body { "${x}" } } }
+      text Body { "B" }
+    }
+  }
+}`
+	res := buildWithData(t, dslText, map[string]any{"empty": []any{}})
+	texts := res.Pages[0].Texts
+	if len(texts) != 2 {
+		t.Fatalf("期望 2 个文本框，got %d", len(texts))
+	}
+	if want := 30 + parseLength("10pt"); !eq(texts[1].Y, want) {
+		t.Fatalf("B 的位置不符: got %.3f, want %.3f", texts[1].Y, want)
+	}
+}
@@ -0,0 +1,828 @@
+package layout
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ByLCY/papyrus/binding"
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// 该文件实现表格布局。表格分两步排版：先收集各行单元格（header/row 与数据驱动的行），
+// 再根据列宽声明与内容确定列宽并逐行排版。
+//
+// 数据驱动的表格写作 `table data.items { columns { column 30% { header: ...; field: ... } } }`，
+// 按数据源数组逐行生成单元格，列的 field 表达式在每行的作用域中求值（行变量默认为 item）。
+
+func handleTable(cmd *dsl.Command, ctx *flowContext, res ResourceSet) error {
+	if cmd.Block == nil {
+		return fmt.Errorf("table 语句缺少内容")
+	}
+	source, args := splitTableSource(cmd)
+	styleName, attrs := parseArgs(args, false)
+	attrs = mergeStyleAttributes(styleName, attrs, res.Styles)
+	before, after := blockMargins(attrs, res)
+	ctx.beginBlock(before)
+
+	width := ctx.width
+	if v := attrs["width"]; v != "" {
+		if w := parseDimension(v, ctx.width); w > 0 {
+			width = w
+		}
+	}
+	rowGap := defaultTableRowGap
+	if v := attrs["row-gap"]; v != "" {
+		if g := parseLength(v); g >= 0 {
+			rowGap = g
+		}
+	} else if v := attrs["rowGap"]; v != "" {
+		if g := parseLength(v); g >= 0 {
+			rowGap = g
+		}
+	}
+	columns := 0
+	if v := attrs["columns"]; v != "" {
+		if c, err := strconv.Atoi(v); err == nil && c > 0 {
+			columns = c
+		}
+	}
+
+	spec, err := collectTableRows(cmd, source, res, ctx.data)
+	if err != nil {
+		return err
+	}
+	base := defaultTableBoxStyle()
+	base.apply(attrs, res)
+	styleTableRows(spec.rows, base, parseStripe(attrs["striped"], res), res)
+	styleTableRows(spec.continued, base, nil, res)
+	// footer 先以全部数据行展开一次，用于确定列数与分页时为本页小计预留的高度
+	allScopes := rowScopes(spec.rows)
+	probe, err := expandTableFooters(spec.footers, map[string][]*binding.Scope{footerFinal: allScopes, footerPage: allScopes, footerRunning: allScopes}, res)
+	if err != nil {
+		return err
+	}
+	// 按声明顺序为单元格分配列位置，跨行单元格占据的位置在后续行中跳过
+	slots, used := placeTableCells(spec.rows)
+	captionSlots, captionCols := placeTableCells(spec.continued)
+	_, footerCols := placeTableCells(probe)
+	colCount := maxInt(maxInt(columns, len(spec.cols)), maxInt(maxInt(used, captionCols), footerCols))
+	if colCount == 0 {
+		return fmt.Errorf("table 需要至少一个单元格")
+	}
+	widths := resolveColumnWidths(spec.cols, colCount, width, spec.rows, slots, res, ctx.typesetter)
+
+	// 各行高度与纵坐标无关：先在 y=0 处排版，放置时再平移到目标位置（分栏中换栏后横坐标也随之平移）
+	layoutX := ctx.baseX
+	rows, err := layoutTableRows(spec.rows, slots, widths, rowGap, layoutX, res, ctx.typesetter, ctx.debug)
+	if err != nil {
+		return err
+	}
+	captions, err := layoutTableRows(spec.continued, captionSlots, widths, rowGap, layoutX, res, ctx.typesetter, ctx.debug)
+	if err != nil {
+		return err
+	}
+	for i := range captions {
+		captions[i].Continued = true
+	}
+	// 由跨行单元格连在一起的行不可拆分；开头以 header 行起始的行组在续页顶部重复
+	groups := groupTableRows(rows)
+	headerCount := 0
+	for headerCount < len(groups) && groups[headerCount][0].IsHeader {
+		headerCount++
+	}
+	headers, body := groups[:headerCount], groups[headerCount:]
+	headerRows := 0
+	for _, group := range headers {
+		headerRows += len(group)
+	}
+
+	layoutFooters := func(rows map[string][]*binding.Scope) ([]TableRow, error) {
+		specs, err := expandTableFooters(spec.footers, rows, res)
+		if err != nil {
+			return nil, err
+		}
+		styleTableRows(specs, base, nil, res)
+		slots, _ := placeTableCells(specs)
+		return layoutTableRows(specs, slots, widths, rowGap, layoutX, res, ctx.typesetter, ctx.debug)
+	}
+	pageFooters, err := layoutFooters(map[string][]*binding.Scope{footerPage: allScopes, footerRunning: allScopes})
+	if err != nil {
+		return err
+	}
+	reserve := 0.0
+	for _, row := range pageFooters {
+		reserve += row.Height + rowGap
+	}
+
+	var table TableBox
+	cursorY := ctx.cursorY
+	bodyRows := 0
+	place := func(rows []TableRow) {
+		for _, row := range rows {
+			table.Rows = append(table.Rows, placeTableRow(row, ctx.baseX-layoutX, cursorY))
+			cursorY += row.Height + rowGap
+		}
+	}
+	start := func(continued bool) {
+		table = TableBox{
+			X:            ctx.baseX,
+			Y:            ctx.cursorY,
+			RowGap:       rowGap,
+			ColumnWidths: widths,
+			BorderColor:  defaultTableBorderColor,
+		}
+		for _, w := range widths {
+			table.Width += w
+		}
+		cursorY = ctx.cursorY
+		bodyRows = 0
+		if continued {
+			place(captions)
+		}
+		for _, group := range headers {
+			place(group)
+		}
+	}
+	finish := func() {
+		if acc := ctx.acc(); acc != nil {
+			acc.appendTable(table)
+		}
+		ctx.cursorY = cursorY
+		if len(table.Rows) > 0 {
+			ctx.cursorY -= rowGap
+		}
+	}
+	// 在行之间分页：已放置的行留在本页，本页底部输出小计，续页重复表头
+	var pageScopes, doneScopes []*binding.Scope
+	breakPage := func() error {
+		subtotal, err := layoutFooters(map[string][]*binding.Scope{footerPage: pageScopes, footerRunning: doneScopes})
+		if err != nil {
+			return err
+		}
+		place(subtotal)
+		finish()
+		ctx.pageBreak()
+		start(true)
+		pageScopes = nil
+		return nil
+	}
+
+	start(false)
+	next := headerRows
+	for _, group := range body {
+		height := -rowGap
+		for _, row := range group {
+			height += row.Height + rowGap
+		}
+		if ctx.allowPageBreak && cursorY+height+reserve > ctx.contentBottom() {
+			switch {
+			case bodyRows > 0:
+				if err := breakPage(); err != nil {
+					return err
+				}
+			case !ctx.atTop():
+				// 表头之后连一行都放不下：整张表格移到下一页
+				ctx.pageBreak()
+				start(false)
+			}
+		}
+		place(group)
+		for _, row := range spec.rows[next : next+len(group)] {
+			if row.scope != nil {
+				pageScopes = append(pageScopes, row.scope)
+				doneScopes = append(doneScopes, row.scope)
+			}
+		}
+		next += len(group)
+		bodyRows++
+	}
+	if len(body) == 0 && ctx.allowPageBreak && cursorY-rowGap > ctx.contentBottom() && !ctx.atTop() {
+		ctx.pageBreak()
+		start(false)
+	}
+	totals, err := layoutFooters(map[string][]*binding.Scope{footerFinal: allScopes})
+	if err != nil {
+		return err
+	}
+	if len(totals) > 0 {
+		height := -rowGap
+		for _, row := range totals {
+			height += row.Height + rowGap
+		}
+		if ctx.allowPageBreak && bodyRows > 0 && cursorY+height > ctx.contentBottom() {
+			if err := breakPage(); err != nil {
+				return err
+			}
+		}
+		place(totals)
+	}
+	finish()
+	ctx.endBlock(after)
+	return nil
+}
+
+// placeTableRow 返回横向平移 dx、并平移到纵坐标 y 的行副本（通常由 y=0 处排版的行平移而来）。
+func placeTableRow(row TableRow, dx, y float64) TableRow {
+	dy := y - row.Y
+	cells := make([]TableCell, len(row.Cells))
+	for i, cell := range row.Cells {
+		cell.X, cell.Y = cell.X+dx, cell.Y+dy
+		cell.Text.X, cell.Text.Y = cell.Text.X+dx, cell.Text.Y+dy
+		cell.Content = shiftCellContent(cell.Content, dx, dy)
+		cells[i] = cell
+	}
+	row.Cells = cells
+	row.Y = y
+	return row
+}
+
+// tableRowSpec 是待排版的一行：单元格、行上声明的属性以及计算后的外观（见 styleTableRows）。
+// 数据行（由数据源生成或以 row 声明）带有该行的作用域，供合计行中的聚合函数使用。
+type tableRowSpec struct {
+	cells  []tableCellSpec
+	header bool
+	footer bool
+	attrs  map[string]string
+	box    tableBoxStyle
+	scope  *binding.Scope
+}
+
+// tableCellSpec 是待排版的单元格：样式、属性、已展开的文本与跨越的列数、行数（0 按 1 处理）。
+// 富内容单元格的 block 不为空，在 data 作用域中按 flow 排版，content 为空。
+type tableCellSpec struct {
+	style   string
+	attrs   map[string]string
+	content string
+	block   *dsl.Block
+	data    any
+	colSpan int
+	rowSpan int
+	box     tableBoxStyle
+}
+
+// tableSpec 是收集到的表格内容：按顺序排列的行、仅在续页顶部显示的 continued 行、columns 中声明的列，
+// 以及在放置时才展开的 footer 合计行。
+type tableSpec struct {
+	rows      []tableRowSpec
+	continued []tableRowSpec
+	cols      []tableColumn
+	footers   []tableFooter
+}
+
+// collectTableRows 按声明顺序收集表格的所有行（控制语句已展开）。
+func collectTableRows(cmd *dsl.Command, source *tableSource, res ResourceSet, data any) (tableSpec, error) {
+	var spec tableSpec
+	err := walkStatements(cmd.Block, data, func(stmt *dsl.Statement, data any) error {
+		if stmt.Command == nil {
+			return nil
+		}
+		switch stmt.Command.Name {
+		case "header", "row", "continued":
+			cells, err := collectTableCells(stmt.Command, res, data)
+			if err != nil {
+				return err
+			}
+			styleName, attrs := parseTableArgs(stmt.Command.Args)
+			attrs = mergeStyleAttributes(styleName, attrs, res.Styles)
+			row := tableRowSpec{cells: cells, header: stmt.Command.Name == "header", attrs: attrs}
+			if stmt.Command.Name == "row" {
+				// 字面量 row 同样参与聚合，作用域即其所在位置（如 for 循环）的作用域
+				row.scope = binding.ScopeOf(data)
+			}
+			if stmt.Command.Name == "continued" {
+				spec.continued = append(spec.continued, row)
+			} else {
+				spec.rows = append(spec.rows, row)
+			}
+		case "columns":
+			if spec.cols != nil {
+				return fmt.Errorf("%s: table 中只能声明一个 columns", stmt.Command.Pos)
+			}
+			cols, err := parseTableColumns(stmt.Command, res, data)
+			if err != nil {
+				return err
+			}
+			spec.cols = cols
+			return buildDataRows(cols, source, res, data, func(cells []tableCellSpec, header bool, scope *binding.Scope) error {
+				spec.rows = append(spec.rows, tableRowSpec{cells: cells, header: header, scope: scope})
+				return nil
+			})
+		case "footer":
+			mode, args := footerMode(stmt.Command.Args)
+			spec.footers = append(spec.footers, tableFooter{cmd: stmt.Command, mode: mode, args: args, data: data})
+		}
+		return nil
+	})
+	return spec, err
+}
+
+// collectTableCells 收集 header/row/continued 中的 cell，空文本的 cell 会被跳过。
+func collectTableCells(cmd *dsl.Command, res ResourceSet, data any) ([]tableCellSpec, error) {
+	if cmd.Block == nil {
+		return nil, fmt.Errorf("%s: %s 缺少 cell 定义", cmd.Pos, cmd.Name)
+	}
+	var cells []tableCellSpec
+	err := walkStatements(cmd.Block, data, func(stmt *dsl.Statement, data any) error {
+		if stmt.Command == nil || stmt.Command.Name != "cell" {
+			return nil
+		}
+		args, colSpan, rowSpan, err := splitCellSpans(stmt.Command.Args)
+		if err != nil {
+			return fmt.Errorf("%s: %w", stmt.Command.Pos, err)
+		}
+		styleName, attrs := parseTableArgs(args)
+		attrs = mergeStyleAttributes(styleName, attrs, res.Styles)
+		if hasCommands(stmt.Command.Block) {
+			cells = append(cells, tableCellSpec{style: styleName, attrs: attrs, block: stmt.Command.Block, data: data, colSpan: colSpan, rowSpan: rowSpan})
+			return nil
+		}
+		if extractText(stmt.Command.Block) == "" {
+			return nil
+		}
+		content, err := expandText(stmt.Command.Block, data)
+		if err != nil {
+			return err
+		}
+		cells = append(cells, tableCellSpec{style: styleName, attrs: attrs, content: content, colSpan: colSpan, rowSpan: rowSpan})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(cells) == 0 {
+		return nil, fmt.Errorf("%s: %s 中至少需要一个 cell", cmd.Pos, cmd.Name)
+	}
+	return cells, nil
+}
+
+// parseTableArgs 解析 header/row/cell/column 的参数：参数个数为奇数时第一个标识符是样式名
+// （`cell BodyBold align right`），否则全部按属性对处理（`cell valign bottom`）。
+func parseTableArgs(args []*dsl.Lexeme) (string, map[string]string) {
+	return parseArgs(args, len(args)%2 == 1)
+}
+
+// splitCellSpans 从 cell 参数中取出 `colspan N` 与 `rowspan N`，其余参数原样返回。
+// 跨度需在解析样式名之前取出，否则 `cell colspan 2` 中的 colspan 会被当作样式名。
+func splitCellSpans(args []*dsl.Lexeme) ([]*dsl.Lexeme, int, int, error) {
+	colSpan, rowSpan := 1, 1
+	rest := make([]*dsl.Lexeme, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg.Type != "Ident" || (arg.Value != "colspan" && arg.Value != "rowspan") {
+			rest = append(rest, arg)
+			continue
+		}
+		if i+1 >= len(args) {
+			return nil, 0, 0, fmt.Errorf("%s 缺少跨度", arg.Value)
+		}
+		n, err := strconv.Atoi(args[i+1].Value)
+		if err != nil || n < 1 {
+			return nil, 0, 0, fmt.Errorf("%s 应为正整数: %s", arg.Value, args[i+1].Value)
+		}
+		if arg.Value == "colspan" {
+			colSpan = n
+		} else {
+			rowSpan = n
+		}
+		i++
+	}
+	return rest, colSpan, rowSpan, nil
+}
+
+// tableCellSlot 是单元格在表格网格中的位置。
+type tableCellSlot struct {
+	col     int
+	colSpan int
+	rowSpan int
+}
+
+// placeTableCells 按声明顺序为各行单元格分配起始列：跳过被上方跨行单元格占据的位置，
+// rowspan 超出剩余行数时截断到最后一行。返回每个单元格的位置与所需的列数。
+func placeTableCells(rows []tableRowSpec) ([][]tableCellSlot, int) {
+	occupied := make([]map[int]bool, len(rows))
+	for i := range occupied {
+		occupied[i] = map[int]bool{}
+	}
+	slots := make([][]tableCellSlot, len(rows))
+	cols := 0
+	for r, row := range rows {
+		col := 0
+		for _, cell := range row.cells {
+			for occupied[r][col] {
+				col++
+			}
+			slot := tableCellSlot{col: col, colSpan: maxInt(cell.colSpan, 1), rowSpan: minInt(maxInt(cell.rowSpan, 1), len(rows)-r)}
+			for dr := 0; dr < slot.rowSpan; dr++ {
+				for dc := 0; dc < slot.colSpan; dc++ {
+					occupied[r+dr][col+dc] = true
+				}
+			}
+			slots[r] = append(slots[r], slot)
+			col += slot.colSpan
+			cols = maxInt(cols, col)
+		}
+	}
+	return slots, cols
+}
+
+// layoutTableRows 在 y=0 处排版各行：单元格宽度为所跨列宽之和，行高取不跨行单元格的最大高度；
+// 跨行单元格放不下时加高其跨越的最后一行。单元格高度为所跨各行高度与行距之和，文本按 valign 在其中垂直对齐。
+func layoutTableRows(specs []tableRowSpec, slots [][]tableCellSlot, widths []float64, rowGap, baseX float64, res ResourceSet, ts Typesetter, debug DebugOptions) ([]TableRow, error) {
+	type spanNeed struct {
+		row, span int
+		height    float64
+	}
+	var spans []spanNeed
+	rows := make([]TableRow, len(specs))
+	needs := make([][]float64, len(specs))
+	for r, spec := range specs {
+		row := TableRow{IsHeader: spec.header, IsFooter: spec.footer, Background: spec.box.background, Border: spec.box.border}
+		for i, cell := range spec.cells {
+			slot := slots[r][i]
+			if slot.col+slot.colSpan > len(widths) {
+				return nil, fmt.Errorf("表格行中的单元格超出表格的 %d 列", len(widths))
+			}
+			x := baseX
+			for _, w := range widths[:slot.col] {
+				x += w
+			}
+			colWidth := 0.0
+			for _, w := range widths[slot.col : slot.col+slot.colSpan] {
+				colWidth += w
+			}
+			pad := cell.box.padding
+			cellWidth := colWidth - pad.Left - pad.Right
+			if cellWidth <= 0 {
+				cellWidth = colWidth
+			}
+			// 单元格折行策略：默认继承表/flow 含义不易获取，这里按属性值或默认 anywhere
+			wrap := normalizeWrap(cell.attrs["wrap"])
+			if wrap == "" {
+				wrap = "anywhere"
+			}
+			tc := TableCell{
+				X: x, Width: colWidth,
+				Col: slot.col, ColSpan: slot.colSpan, RowSpan: slot.rowSpan,
+				Background: cell.box.background, Border: cell.box.border, Padding: pad, VAlign: cell.box.valign,
+			}
+			var height float64
+			var err error
+			if cell.block != nil {
+				tc.Content, height, err = layoutCellContent(cell.block, cell.data, x+pad.Left, pad.Top, cellWidth, res, ts, debug)
+			} else {
+				tc.Text, height, err = composeTextBox(cell.style, cell.attrs, cell.content, x+pad.Left, pad.Top, cellWidth, res, ts, debug, wrap)
+			}
+			if err != nil {
+				return nil, err
+			}
+			row.Cells = append(row.Cells, tc)
+			need := height + pad.Top + pad.Bottom
+			needs[r] = append(needs[r], need)
+			if slot.rowSpan == 1 {
+				row.Height = math.Max(row.Height, need)
+			} else {
+				spans = append(spans, spanNeed{row: r, span: slot.rowSpan, height: need})
+			}
+		}
+		rows[r] = row
+	}
+	spanHeight := func(r, span int) float64 {
+		h := float64(span-1) * rowGap
+		for _, row := range rows[r : r+span] {
+			h += row.Height
+		}
+		return h
+	}
+	for _, s := range spans {
+		if extra := s.height - spanHeight(s.row, s.span); extra > 0 {
+			rows[s.row+s.span-1].Height += extra
+		}
+	}
+	for r := range rows {
+		for i := range rows[r].Cells {
+			cell := &rows[r].Cells[i]
+			cell.Height = spanHeight(r, cell.RowSpan)
+			var offset float64
+			switch cell.VAlign {
+			case "middle":
+				offset = (cell.Height - needs[r][i]) / 2
+			case "bottom":
+				offset = cell.Height - needs[r][i]
+			}
+			if offset > 0 {
+				cell.Text.Y += offset
+				cell.Content = shiftCellContent(cell.Content, 0, offset)
+			}
+		}
+	}
+	return rows, nil
+}
+
+// groupTableRows 将由跨行单元格连在一起的行划为一组，分页时整组放置。
+func groupTableRows(rows []TableRow) [][]TableRow {
+	var groups [][]TableRow
+	for start := 0; start < len(rows); {
+		end := start + 1
+		for r := start; r < end; r++ {
+			for _, cell := range rows[r].Cells {
+				end = maxInt(end, r+cell.RowSpan)
+			}
+		}
+		groups = append(groups, rows[start:end])
+		start = end
+	}
+	return groups
+}
+
+// columnWidth 是列宽声明。
+type columnWidth struct {
+	kind  string  // fixed（mm）、percent（占表格宽度的百分比）、auto（按内容）或 fr（按比例分配剩余宽度）
+	value float64 // fixed 为毫米，percent 为百分数，fr 为权重
+}
+
+// parseColumnWidth 解析列宽：30mm/12pt 等长度、30%、auto、*、2*、2fr；未声明或无法识别时按 1fr 处理。
+func parseColumnWidth(v string) columnWidth {
+	v = strings.ToLower(strings.TrimSpace(v))
+	switch {
+	case v == "" || v == "*":
+		return columnWidth{kind: "fr", value: 1}
+	case v == "auto":
+		return columnWidth{kind: "auto"}
+	case strings.HasSuffix(v, "fr") || strings.HasSuffix(v, "*"):
+		num := strings.TrimSuffix(strings.TrimSuffix(v, "fr"), "*")
+		if f, err := strconv.ParseFloat(num, 64); err == nil && f > 0 {
+			return columnWidth{kind: "fr", value: f}
+		}
+	case strings.HasSuffix(v, "%"):
+		if f, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64); err == nil && f > 0 {
+			return columnWidth{kind: "percent", value: f}
+		}
+	default:
+		if l := parseLength(v); l > 0 {
+			return columnWidth{kind: "fixed", value: l}
+		}
+	}
+	return columnWidth{kind: "fr", value: 1}
+}
+
+// resolveColumnWidths 计算各列宽度：固定与百分比列直接换算，auto 列取该列单元格不折行时的最大宽度，
+// fr 列按权重分配剩余宽度。没有 fr 列且内容过宽时按比例压缩 auto 列；未声明宽度的列按 1fr 处理。
+func resolveColumnWidths(cols []tableColumn, colCount int, tableWidth float64, rows []tableRowSpec, slots [][]tableCellSlot, res ResourceSet, ts Typesetter) []float64 {
+	specs := make([]columnWidth, colCount)
+	for i := range specs {
+		spec := ""
+		if i < len(cols) {
+			spec = cols[i].width
+		}
+		specs[i] = parseColumnWidth(spec)
+	}
+
+	widths := make([]float64, colCount)
+	used, frTotal, autoTotal := 0.0, 0.0, 0.0
+	for i, spec := range specs {
+		switch spec.kind {
+		case "fixed":
+			widths[i] = spec.value
+		case "percent":
+			widths[i] = tableWidth * spec.value / 100
+		case "auto":
+			widths[i] = autoColumnWidth(i, tableWidth, rows, slots, res, ts)
+			autoTotal += widths[i]
+		case "fr":
+			frTotal += spec.value
+		}
+		used += widths[i]
+	}
+
+	remaining := tableWidth - used
+	switch {
+	case frTotal > 0:
+		for i, spec := range specs {
+			if spec.kind == "fr" {
+				widths[i] = math.Max(remaining, 0) * spec.value / frTotal
+			}
+		}
+	case remaining < 0 && autoTotal > 0:
+		scale := math.Max(autoTotal+remaining, 0) / autoTotal
+		for i, spec := range specs {
+			if spec.kind == "auto" {
+				widths[i] *= scale
+			}
+		}
+	}
+	return widths
+}
+
+// autoColumnWidth 返回第 col 列单元格不折行时的最大宽度（含各自的左右内边距）；跨列单元格不参与计算。
+// 富内容单元格按 inferFlowWidth 估算，不超过表格宽度。
+func autoColumnWidth(col int, tableWidth float64, rows []tableRowSpec, slots [][]tableCellSlot, res ResourceSet, ts Typesetter) float64 {
+	width := 0.0
+	for r, row := range rows {
+		for i, cell := range row.cells {
+			if slots[r][i].col != col || slots[r][i].colSpan != 1 {
+				continue
+			}
+			pad := cell.box.padding
+			if cell.block != nil {
+				width = math.Max(width, inferFlowWidth(cell.block, res, tableWidth, ts, cell.data)+pad.Left+pad.Right)
+				continue
+			}
+			content, _ := parseInlineTypst(cell.content)
+			width = math.Max(width, measureTextWidth(cell.style, cell.attrs, content, res, ts)+pad.Left+pad.Right)
+		}
+	}
+	return width
+}
+
+// tableSource 是数据驱动表格的数据源，写作 `table data.items` 或 `table line in data.items`。
+type tableSource struct {
+	name string // 行变量名
+	args []*dsl.Lexeme
+}
+
+// tableColumn 描述 columns 中的一列。
+type tableColumn struct {
+	width       string     // 列宽声明（如 30%）
+	header      *dsl.Value // 表头文本，可为字符串（支持插值）或表达式
+	headerStyle string
+	field       *dsl.Value // 单元格取值，可为表达式（如 item.qty）或字符串（支持插值）
+	style       string
+	attrs       map[string]string // 单元格文本属性（align/font/size/color/wrap 等）
+}
+
+// splitTableSource 从 table 参数中分离数据源表达式，返回数据源与其余属性参数。
+// 只有包含 columns 定义的表格才有数据源，其余表格的参数原样返回。
+func splitTableSource(cmd *dsl.Command) (*tableSource, []*dsl.Lexeme) {
+	if !hasColumns(cmd.Block) {
+		return nil, cmd.Args
+	}
+	name, source, rest := dsl.SourceArgs(cmd.Args)
+	if source == nil {
+		return nil, cmd.Args
+	}
+	if name == "" {
+		name = "item"
+	}
+	return &tableSource{name: name, args: source}, rest
+}
+
+func hasColumns(block *dsl.Block) bool {
+	if block == nil {
+		return false
+	}
+	for _, stmt := range block.Statements {
+		if stmt.Command != nil && stmt.Command.Name == "columns" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTableColumns 解析 `columns { column [宽度] [样式] { header: ...; field: ... } }`，
+// columns 中同样可以使用控制语句（例如按条件显示某一列）。
+func parseTableColumns(cmd *dsl.Command, res ResourceSet, data any) ([]tableColumn, error) {
+	var cols []tableColumn
+	err := walkStatements(cmd.Block, data, func(stmt *dsl.Statement, data any) error {
+		if stmt.Command == nil || stmt.Command.Name != "column" {
+			return nil
+		}
+		col := tableColumn{attrs: map[string]string{}}
+		args := stmt.Command.Args
+		col.width, args = columnWidthArg(args)
+		col.style, col.attrs = parseTableArgs(args)
+		if stmt.Command.Block != nil {
+			for _, inner := range stmt.Command.Block.Statements {
+				assign := inner.Assignment
+				if assign == nil {
+					continue
+				}
+				switch assign.Key {
+				case "header":
+					col.header = assign.Value
+				case "field":
+					col.field = assign.Value
+				case "style":
+					col.style = valueToString(assign.Value)
+				case "header-style":
+					col.headerStyle = valueToString(assign.Value)
+				case "width":
+					col.width = valueToString(assign.Value)
+				default:
+					col.attrs[assign.Key] = valueToString(assign.Value)
+				}
+			}
+		}
+		cols = append(cols, col)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("%s: columns 中至少需要一个 column", cmd.Pos)
+	}
+	return cols, nil
+}
+
+// columnWidthArg 读取 column 的首个参数中的列宽（30%、40mm、auto、*、2*、2fr），返回列宽与其余参数。
+func columnWidthArg(args []*dsl.Lexeme) (string, []*dsl.Lexeme) {
+	if len(args) == 0 {
+		return "", args
+	}
+	first := args[0]
+	switch {
+	case first.Type == "Number":
+		if len(args) > 1 && (args[1].Value == "*" || args[1].Value == "fr") {
+			return first.Value + args[1].Value, args[2:]
+		}
+		return first.Value, args[1:]
+	case first.Value == "auto" || first.Value == "*":
+		return first.Value, args[1:]
+	}
+	return "", args
+}
+
+// buildDataRows 依次生成表头行（任一列声明了 header 时）与数据行（有数据源时），交给 emit 排版。
+func buildDataRows(cols []tableColumn, source *tableSource, res ResourceSet, data any, emit func(cells []tableCellSpec, header bool, scope *binding.Scope) error) error {
+	scope := binding.ScopeOf(data)
+	hasHeader := false
+	for _, col := range cols {
+		if col.header != nil {
+			hasHeader = true
+		}
+	}
+	if hasHeader {
+		cells := make([]tableCellSpec, len(cols))
+		for i, col := range cols {
+			content, err := expandInlineValue(col.header, scope)
+			if err != nil {
+				return err
+			}
+			attrs := map[string]string{}
+			if align := col.attrs["align"]; align != "" {
+				attrs["align"] = align
+			}
+			cells[i] = tableCellSpec{style: col.headerStyle, attrs: mergeStyleAttributes(col.headerStyle, attrs, res.Styles), content: content}
+		}
+		if err := emit(cells, true, nil); err != nil {
+			return err
+		}
+	}
+
+	if source == nil {
+		return nil
+	}
+	node, err := dsl.ParseLexemes(source.args)
+	if err != nil {
+		return err
+	}
+	val, err := binding.Eval(node, scope)
+	if err != nil {
+		return err
+	}
+	items, ok := binding.Items(val)
+	if !ok {
+		return fmt.Errorf("%s: table 的数据源应为数组，实际为 %T", node.ExprPos(), val)
+	}
+	for i, item := range items {
+		iter := scope.Child()
+		iter.Set(source.name, item)
+		iter.Set("loop", loopMeta(i, len(items)))
+		cells := make([]tableCellSpec, len(cols))
+		for j, col := range cols {
+			content, err := expandInlineValue(col.field, iter)
+			if err != nil {
+				return err
+			}
+			cells[j] = tableCellSpec{style: col.style, attrs: mergeStyleAttributes(col.style, col.attrs, res.Styles), content: content}
+		}
+		if err := emit(cells, false, iter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandInlineValue 将属性值展开为单元格文本：表达式按插值规则求值，字符串展开其中的 ${...}；
+// 插值结果经 escapeInline 转义，与 text 块的处理一致。
+func expandInlineValue(val *dsl.Value, data any) (string, error) {
+	if val == nil {
+		return "", nil
+	}
+	if val.Expr != nil {
+		node, err := val.Expr.AST()
+		if err != nil {
+			return "", err
+		}
+		return binding.ExpandExpr(node, data, escapeInline)
+	}
+	if val.String != nil {
+		pos := val.Pos
+		pos.Column++
+		pos.Offset++
+		return binding.ExpandMarkup(string(*val.String), pos, data, escapeInline)
+	}
+	return valueToString(val), nil
+}
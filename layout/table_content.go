@@ -0,0 +1,133 @@
+package layout
+
+import (
+	"math"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// 富内容单元格：cell 块中包含命令（text、image、flow、table、形状等）时，按不分页的 flow 排版，
+// 结果保存在 TableCell.Content 中；只有文本的单元格仍使用 TableCell.Text。
+
+// hasCommands 判断块中是否包含命令语句（控制语句也算在内）。
+func hasCommands(block *dsl.Block) bool {
+	if block == nil {
+		return false
+	}
+	for _, stmt := range block.Statements {
+		if stmt.Command != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// layoutCellContent 以 (x, y) 为左上角、width 为宽度排版单元格中的内容，返回内容及其高度。
+// 单元格内不分页；形状（line/rect/circle）的坐标相对于内容区域左上角。
+func layoutCellContent(block *dsl.Block, data any, x, y, width float64, res ResourceSet, ts Typesetter, debug DebugOptions) (*CellContent, float64, error) {
+	return layoutIsolated(data, x, y, width, ts, debug, func(ctx *flowContext) error {
+		return processBlock(block, ctx, res)
+	})
+}
+
+// layoutIsolated 在独立的、不分页的上下文中执行 layout，返回其生成的内容及高度；grid、row 等容器也用它排版子项。
+func layoutIsolated(data any, x, y, width float64, ts Typesetter, debug DebugOptions, layout func(ctx *flowContext) error) (*CellContent, float64, error) {
+	collector := newPageCollector(0, 0, Margin{})
+	collector.originX, collector.originY = x, y
+	ctx := &flowContext{
+		baseX:      x,
+		baseY:      y,
+		width:      width,
+		cursorY:    y,
+		data:       data,
+		typesetter: ts,
+		debug:      debug,
+		collector:  collector,
+		textWrap:   "anywhere",
+	}
+	if err := layout(ctx); err != nil {
+		return nil, 0, err
+	}
+	acc := collector.curr()
+	content := &CellContent{Texts: acc.texts, Images: acc.images, Tables: acc.tables, Lines: acc.lines, Rects: acc.rects, Circles: acc.circles}
+	return content, math.Max(content.bottom()-y, 0), nil
+}
+
+// bottom 返回内容中文本、图片、表格与形状的最大纵坐标。
+func (c *CellContent) bottom() float64 {
+	bottom := math.Inf(-1)
+	for _, tb := range c.Texts {
+		bottom = math.Max(bottom, tb.Y+tb.Height)
+	}
+	for _, img := range c.Images {
+		bottom = math.Max(bottom, img.Y+img.Height)
+	}
+	for _, t := range c.Tables {
+		for _, row := range t.Rows {
+			bottom = math.Max(bottom, row.Y+row.Height)
+			for _, cell := range row.Cells {
+				bottom = math.Max(bottom, cell.Y+cell.Height)
+			}
+		}
+	}
+	for _, ln := range c.Lines {
+		bottom = math.Max(bottom, math.Max(ln.Y1, ln.Y2))
+	}
+	for _, rc := range c.Rects {
+		bottom = math.Max(bottom, rc.Y+rc.Height)
+	}
+	for _, circle := range c.Circles {
+		bottom = math.Max(bottom, circle.CY+circle.R)
+	}
+	return bottom
+}
+
+// shiftCellContent 返回平移 (dx, dy) 后的内容副本；表头行会在每个续页重复放置，因此不能原地修改。
+func shiftCellContent(c *CellContent, dx, dy float64) *CellContent {
+	if c == nil {
+		return nil
+	}
+	out := &CellContent{
+		Texts:   make([]TextBox, len(c.Texts)),
+		Images:  make([]ImageBox, len(c.Images)),
+		Tables:  make([]TableBox, len(c.Tables)),
+		Lines:   make([]Line, len(c.Lines)),
+		Rects:   make([]Rect, len(c.Rects)),
+		Circles: make([]Circle, len(c.Circles)),
+	}
+	for i, tb := range c.Texts {
+		tb.X, tb.Y = tb.X+dx, tb.Y+dy
+		out.Texts[i] = tb
+	}
+	for i, img := range c.Images {
+		img.X, img.Y = img.X+dx, img.Y+dy
+		out.Images[i] = img
+	}
+	for i, t := range c.Tables {
+		out.Tables[i] = shiftTable(t, dx, dy)
+	}
+	for i, ln := range c.Lines {
+		ln.X1, ln.Y1, ln.X2, ln.Y2 = ln.X1+dx, ln.Y1+dy, ln.X2+dx, ln.Y2+dy
+		out.Lines[i] = ln
+	}
+	for i, rc := range c.Rects {
+		rc.X, rc.Y = rc.X+dx, rc.Y+dy
+		out.Rects[i] = rc
+	}
+	for i, circle := range c.Circles {
+		circle.CX, circle.CY = circle.CX+dx, circle.CY+dy
+		out.Circles[i] = circle
+	}
+	return out
+}
+
+// shiftTable 返回平移 (dx, dy) 后的表格副本。
+func shiftTable(t TableBox, dx, dy float64) TableBox {
+	rows := make([]TableRow, len(t.Rows))
+	for i, row := range t.Rows {
+		rows[i] = placeTableRow(row, dx, row.Y+dy)
+	}
+	t.Rows = rows
+	t.X, t.Y = t.X+dx, t.Y+dy
+	return t
+}
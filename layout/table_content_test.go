@@ -0,0 +1,82 @@
+package layout
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// TestTableRichCells 验证包含命令的单元格按 flow 排版：行高取内容高度，文本、图片、嵌套表格与形状随行定位。
+func TestTableRichCells(t *testing.T) {
+	dslText := `doc T v1 {
+  resources {
+    font Body { src: "x.ttf" }
+    image Thumb { src: "thumb.png" }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      table width 100mm {
+        columns { column auto; column * }
+        row {
+          cell {
+            image Thumb width 20mm height 15mm
+            rect x 0 y 0 width 2mm height 2mm
+          }
+          cell {
+            text { "名称" }
+            table { row { cell { "规格" } cell { "A" } } }
+          }
+        }
+        row { cell { "x" } cell { "y" } }
+      }
+    }
+  }
+}`
+	doc, err := dsl.Parse(strings.NewReader(dslText))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	res, err := Build(doc, nil, BuildOptions{Typesetter: measureTypesetter{}})
+	if err != nil {
+		t.Fatalf("布局计算失败: %v", err)
+	}
+	table := res.Pages[0].Tables[0]
+	if len(res.Pages[0].Tables) != 1 || len(res.Pages[0].Images) != 0 {
+		t.Fatalf("单元格内容不应出现在页面上")
+	}
+	if got := table.ColumnWidths[0]; math.Abs(got-(20+2*cellPadding)) > 1e-9 {
+		t.Fatalf("auto 列应按图片宽度估算: %v", got)
+	}
+
+	row := table.Rows[0]
+	thumb := row.Cells[0].Content
+	if thumb == nil || len(thumb.Images) != 1 || len(thumb.Rects) != 1 {
+		t.Fatalf("第一个单元格应包含图片与矩形: %+v", thumb)
+	}
+	if img := thumb.Images[0]; math.Abs(img.X-(table.X+cellPadding)) > 1e-9 || math.Abs(img.Y-(row.Y+cellPadding)) > 1e-9 {
+		t.Fatalf("图片应位于单元格内边距内: %+v row.Y=%v", img, row.Y)
+	}
+	if rc := thumb.Rects[0]; math.Abs(rc.X-(table.X+cellPadding)) > 1e-9 || math.Abs(rc.Y-(row.Y+cellPadding)) > 1e-9 {
+		t.Fatalf("形状坐标应相对于单元格内容区域: %+v", rc)
+	}
+	if math.Abs(row.Height-(15+2*cellPadding)) > 1e-9 {
+		t.Fatalf("行高应由最高的内容决定: %v", row.Height)
+	}
+
+	info := row.Cells[1].Content
+	if info == nil || len(info.Texts) != 1 || len(info.Tables) != 1 {
+		t.Fatalf("第二个单元格应包含文本与嵌套表格: %+v", info)
+	}
+	nested := info.Tables[0]
+	if nested.Y <= info.Texts[0].Y || nested.X != row.Cells[1].X+cellPadding || math.Abs(nested.Width-(row.Cells[1].Width-2*cellPadding)) > 1e-9 {
+		t.Fatalf("嵌套表格应位于文本之后并占满单元格内容宽度: %+v", nested)
+	}
+	if cell := nested.Rows[0].Cells[1]; cell.Text.Content != "A" || cell.Y < nested.Y {
+		t.Fatalf("嵌套表格的单元格位置错误: %+v", cell)
+	}
+	if table.Rows[1].Cells[0].Content != nil || table.Rows[1].Cells[0].Text.Content != "x" {
+		t.Fatalf("纯文本单元格仍使用 Text")
+	}
+}
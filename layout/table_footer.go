@@ -0,0 +1,62 @@
+package layout
+
+import (
+	"github.com/ByLCY/papyrus/binding"
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// 合计行：table 中的 `footer { cell ... }` 在最后一行之后输出；`footer page { ... }` 与 `footer running { ... }`
+// 在表格分页处输出于每段表格底部，分别汇总本页的数据行与截至本页的全部数据行。
+// footer 中的单元格在放置时才展开，聚合函数（sum/count/avg/min/max）遍历对应的数据行（见 binding.Scope.Aggregate）。
+
+const (
+	footerFinal   = ""        // 表格末尾的合计
+	footerPage    = "page"    // 本页小计
+	footerRunning = "running" // 截至本页的累计
+)
+
+// tableFooter 是 footer 声明及其所在的作用域。
+type tableFooter struct {
+	cmd  *dsl.Command
+	mode string
+	args []*dsl.Lexeme // 去掉 page/running 之后的参数
+	data any
+}
+
+// footerMode 取出 footer 的第一个参数 page 或 running，其余参数原样返回。
+func footerMode(args []*dsl.Lexeme) (string, []*dsl.Lexeme) {
+	if len(args) > 0 && args[0].Type == "Ident" && (args[0].Value == footerPage || args[0].Value == footerRunning) {
+		return args[0].Value, args[1:]
+	}
+	return footerFinal, args
+}
+
+// expandTableFooters 按声明顺序展开 rows 中列出的各类 footer，聚合函数遍历该类对应的数据行作用域。
+func expandTableFooters(footers []tableFooter, rows map[string][]*binding.Scope, res ResourceSet) ([]tableRowSpec, error) {
+	var specs []tableRowSpec
+	for _, f := range footers {
+		scopes, ok := rows[f.mode]
+		if !ok {
+			continue
+		}
+		cells, err := collectTableCells(f.cmd, res, binding.ScopeOf(f.data).Aggregate(scopes))
+		if err != nil {
+			return nil, err
+		}
+		styleName, attrs := parseTableArgs(f.args)
+		attrs = mergeStyleAttributes(styleName, attrs, res.Styles)
+		specs = append(specs, tableRowSpec{cells: cells, footer: true, attrs: attrs})
+	}
+	return specs, nil
+}
+
+// rowScopes 返回数据行的作用域，表头行没有作用域。
+func rowScopes(rows []tableRowSpec) []*binding.Scope {
+	var scopes []*binding.Scope
+	for _, row := range rows {
+		if row.scope != nil {
+			scopes = append(scopes, row.scope)
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,107 @@
+package layout
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// TestTableFooterAggregates 验证 footer 的聚合函数：分页处输出本页小计与累计，最后一页输出合计。
+func TestTableFooterAggregates(t *testing.T) {
+	dslText := `doc T v1 {
+  page A4 portrait margin 100mm {
+    flow {
+      table data.items {
+        columns {
+          column { header: "名称"; field: item.name }
+          column { header: "金额"; field: item.amount }
+        }
+        footer page { cell { "本页小计" } cell { "${sum(item.amount)}" } }
+        footer running { cell { "累计" } cell { "${sum(item.amount)}" } }
+        footer background #eee { cell { "合计" } cell { "${sum(item.amount)} / ${count()} / ${max(item.amount)}" } }
+      }
+    }
+  }
+}`
+	items := make([]any, 30)
+	for i := range items {
+		items[i] = map[string]any{"name": fmt.Sprintf("第%d项", i+1), "amount": float64(i + 1)}
+	}
+	res := buildWithData(t, dslText, map[string]any{"items": items})
+	if len(res.Pages) < 2 {
+		t.Fatalf("表格应跨页，实际 %d 页", len(res.Pages))
+	}
+	running := 0
+	for pi, page := range res.Pages {
+		rows := page.Tables[0].Rows
+		last := pi == len(res.Pages)-1
+		pageSum := 0
+		var footers []TableRow
+		for _, row := range rows {
+			switch {
+			case row.IsFooter:
+				footers = append(footers, row)
+			case !row.IsHeader:
+				if len(footers) > 0 {
+					t.Fatalf("第 %d 页的合计行应位于数据行之后", pi+1)
+				}
+				n, err := strconv.Atoi(row.Cells[1].Text.Content)
+				if err != nil {
+					t.Fatalf("金额解析失败: %v", err)
+				}
+				pageSum += n
+			}
+			if row.Y+row.Height > 297-100+1e-9 {
+				t.Fatalf("第 %d 页的行超出内容区域底部", pi+1)
+			}
+		}
+		running += pageSum
+		texts := make([]string, len(footers))
+		for i, row := range footers {
+			texts[i] = row.Cells[0].Text.Content + "=" + row.Cells[1].Text.Content
+		}
+		want := []string{fmt.Sprintf("本页小计=%d", pageSum), fmt.Sprintf("累计=%d", running)}
+		if last {
+			want = []string{"合计=465 / 30 / 30"}
+		}
+		if fmt.Sprint(texts) != fmt.Sprint(want) {
+			t.Fatalf("第 %d 页的合计行错误: %v，期望 %v", pi+1, texts, want)
+		}
+		if last && (footers[0].Background == nil || *footers[0].Background != (Color{R: 238, G: 238, B: 238})) {
+			t.Fatalf("footer 上的属性应作用于合计行: %v", footers[0].Background)
+		}
+	}
+	if running != 465 {
+		t.Fatalf("各页数据行之和应为 465，实际 %d", running)
+	}
+}
+
+// TestTableFooterLiteralRows 验证 for 循环中的字面量 row 同样参与 footer 的聚合。
+func TestTableFooterLiteralRows(t *testing.T) {
+	dslText := `doc T v1 {
+  page A4 portrait margin 10mm {
+    flow {
+      table {
+        header { cell { "名称" } cell { "数量" } }
+        for item in data.items {
+          row { cell { "${item.name}" } cell { "${item.qty}" } }
+        }
+        footer { cell { "合计" } cell { "${sum(item.qty)} / ${count()} / ${avg(item.qty)}" } }
+      }
+    }
+  }
+}`
+	items := []any{
+		map[string]any{"name": "甲", "qty": 2.0},
+		map[string]any{"name": "乙", "qty": 4.0},
+	}
+	res := buildWithData(t, dslText, map[string]any{"items": items})
+	rows := res.Pages[0].Tables[0].Rows
+	last := rows[len(rows)-1]
+	if !last.IsFooter {
+		t.Fatalf("最后一行应为合计行: %+v", last)
+	}
+	if got := last.Cells[1].Text.Content; got != "6 / 2 / 3" {
+		t.Fatalf("字面量行的合计错误: %q", got)
+	}
+}
@@ -0,0 +1,172 @@
+package layout
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 表格外观：table、header/row、cell 上的 background、border、padding、valign 依次层叠，
+// 后者覆盖前者；表头默认浅灰背景，table 的 striped 为数据行设置隔行底色。
+
+const defaultTableBorderWidth = 0.2
+
+var (
+	defaultTableBorderColor = Color{R: 200, G: 200, B: 200}
+	defaultHeaderBackground = Color{R: 248, G: 248, B: 248}
+	defaultStripeBackground = Color{R: 245, G: 245, B: 245}
+)
+
+// tableBoxStyle 是单元格（或行中的空位）的外观。
+type tableBoxStyle struct {
+	background *Color
+	border     Borders
+	padding    Margin
+	valign     string // top、middle 或 bottom
+}
+
+// defaultTableBoxStyle 返回未声明任何外观属性时的单元格外观。
+func defaultTableBoxStyle() tableBoxStyle {
+	b := Border{Width: defaultTableBorderWidth, Color: defaultTableBorderColor}
+	return tableBoxStyle{
+		border:  Borders{Top: b, Right: b, Bottom: b, Left: b},
+		padding: Margin{Top: cellPadding, Right: cellPadding, Bottom: cellPadding, Left: cellPadding},
+		valign:  "top",
+	}
+}
+
+// apply 按 attrs 覆盖外观：先处理 border、padding 等简写，再处理 border-top、padding-left 等单边属性。
+func (s *tableBoxStyle) apply(attrs map[string]string, res ResourceSet) {
+	if v := attrs["background"]; v != "" {
+		if v == "none" {
+			s.background = nil
+		} else {
+			c := resolveColor(v, res)
+			s.background = &c
+		}
+	}
+	sides := []*Border{&s.border.Top, &s.border.Right, &s.border.Bottom, &s.border.Left}
+	if v := attrs["border"]; v != "" {
+		for _, side := range sides {
+			*side = parseBorder(v, *side, res)
+		}
+	}
+	if v := attrs["border-width"]; v != "" {
+		for _, side := range sides {
+			side.Width = parseLength(v)
+		}
+	}
+	if v := attrs["border-color"]; v != "" {
+		for _, side := range sides {
+			side.Color = resolveColor(v, res)
+		}
+	}
+	for i, name := range []string{"top", "right", "bottom", "left"} {
+		if v := attrs["border-"+name]; v != "" {
+			*sides[i] = parseBorder(v, *sides[i], res)
+		}
+	}
+
+	if v := attrs["padding"]; v != "" {
+		s.padding = parsePadding(v, s.padding)
+	}
+	paddings := []*float64{&s.padding.Top, &s.padding.Right, &s.padding.Bottom, &s.padding.Left}
+	for i, name := range []string{"top", "right", "bottom", "left"} {
+		if v := attrs["padding-"+name]; v != "" {
+			*paddings[i] = parseLength(v)
+		}
+	}
+
+	switch strings.ToLower(attrs["valign"]) {
+	case "top":
+		s.valign = "top"
+	case "middle", "center":
+		s.valign = "middle"
+	case "bottom":
+		s.valign = "bottom"
+	}
+}
+
+// parseBorder 解析边框声明，如 `none`、`0.5pt`、`#333` 或 `"0.5pt #333"`；未写出的宽度或颜色沿用 base。
+func parseBorder(value string, base Border, res ResourceSet) Border {
+	out := base
+	for _, field := range strings.Fields(value) {
+		switch {
+		case field == "none":
+			out.Width = 0
+		case isLength(field):
+			out.Width = parseLength(field)
+		default:
+			out.Color = resolveColor(field, res)
+		}
+	}
+	return out
+}
+
+// parsePadding 按 CSS 规则解析 1～4 个长度：上下左右、上下/左右、上/左右/下、上/右/下/左；无法解析时返回 base。
+func parsePadding(value string, base Margin) Margin {
+	var vals []float64
+	for _, field := range strings.Fields(value) {
+		if !isLength(field) {
+			return base
+		}
+		vals = append(vals, parseLength(field))
+	}
+	switch len(vals) {
+	case 1:
+		return Margin{Top: vals[0], Right: vals[0], Bottom: vals[0], Left: vals[0]}
+	case 2:
+		return Margin{Top: vals[0], Right: vals[1], Bottom: vals[0], Left: vals[1]}
+	case 3:
+		return Margin{Top: vals[0], Right: vals[1], Bottom: vals[2], Left: vals[1]}
+	case 4:
+		return Margin{Top: vals[0], Right: vals[1], Bottom: vals[2], Left: vals[3]}
+	}
+	return base
+}
+
+// isLength 判断 value 是否为带可选单位的数值。
+func isLength(value string) bool {
+	_, err := strconv.ParseFloat(trimUnit(value), 64)
+	return err == nil
+}
+
+// parseStripe 解析 table 的 striped 属性：true 使用默认底色，颜色值使用指定底色，false 或未声明时不设隔行底色。
+func parseStripe(value string, res ResourceSet) *Color {
+	switch value {
+	case "", "false", "none":
+		return nil
+	case "true":
+		c := defaultStripeBackground
+		return &c
+	}
+	c := resolveColor(value, res)
+	return &c
+}
+
+// styleTableRows 为各行及其单元格计算外观：表格 → 行 → 单元格依次覆盖。
+// 表头行在表格未声明背景时使用浅灰底色；stripe 不为空时，第 2、4……个数据行（不含表头）使用该底色。
+func styleTableRows(rows []tableRowSpec, base tableBoxStyle, stripe *Color, res ResourceSet) {
+	body := 0
+	for r := range rows {
+		style := base
+		switch {
+		case rows[r].header:
+			if style.background == nil {
+				c := defaultHeaderBackground
+				style.background = &c
+			}
+		default:
+			if stripe != nil && body%2 == 1 {
+				style.background = stripe
+			}
+			body++
+		}
+		style.apply(rows[r].attrs, res)
+		rows[r].box = style
+		for i := range rows[r].cells {
+			cell := style
+			cell.apply(rows[r].cells[i].attrs, res)
+			rows[r].cells[i].box = cell
+		}
+	}
+}
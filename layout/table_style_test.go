@@ -0,0 +1,64 @@
+package layout
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// TestTableStyling 验证 table → row → cell 的外观层叠、单边边框、内边距、垂直对齐与隔行底色。
+func TestTableStyling(t *testing.T) {
+	dslText := `doc T v1 {
+  resources { font Body { src: "x.ttf" } }
+  page A4 portrait margin 10mm {
+    flow {
+      table width 60mm border none padding 2mm striped true {
+        header border-bottom "0.5pt #333" { cell { "名称" } cell { "金额" } }
+        row { cell { "a" } cell { "1" } }
+        row { cell { "b\nb\nb" } cell valign bottom padding-right 4mm { "2" } }
+        row background #ff0 { cell { "c" } cell background none { "3" } }
+      }
+    }
+  }
+}`
+	doc, err := dsl.Parse(strings.NewReader(dslText))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	res, err := Build(doc, nil, BuildOptions{Typesetter: measureTypesetter{}})
+	if err != nil {
+		t.Fatalf("布局计算失败: %v", err)
+	}
+	rows := res.Pages[0].Tables[0].Rows
+
+	head := rows[0].Cells[0]
+	if head.Background == nil || *head.Background != defaultHeaderBackground {
+		t.Fatalf("表头应使用默认底色: %+v", head.Background)
+	}
+	if head.Border.Top.Width != 0 || math.Abs(head.Border.Bottom.Width-0.5*0.352777) > 1e-9 || head.Border.Bottom.Color != (Color{R: 51, G: 51, B: 51}) {
+		t.Fatalf("表头边框应只保留下边: %+v", head.Border)
+	}
+	if head.Padding.Left != 2 || math.Abs(head.Text.X-(head.X+2)) > 1e-9 || math.Abs(head.Text.Y-(head.Y+2)) > 1e-9 {
+		t.Fatalf("表格的 padding 应作用于单元格: %+v", head)
+	}
+
+	if rows[1].Background != nil || rows[2].Background == nil || *rows[2].Background != defaultStripeBackground {
+		t.Fatalf("striped 应为第二个数据行设置底色: %v %v", rows[1].Background, rows[2].Background)
+	}
+	cell := rows[2].Cells[1]
+	if cell.VAlign != "bottom" || math.Abs(cell.Text.Y+cell.Text.Height+cell.Padding.Bottom-(cell.Y+cell.Height)) > 1e-9 {
+		t.Fatalf("valign bottom 应使文本贴近单元格底部: %+v", cell)
+	}
+	if cell.Padding.Right != 4 || cell.Padding.Left != 2 {
+		t.Fatalf("单边 padding 应只覆盖对应的边: %+v", cell.Padding)
+	}
+
+	if bg := rows[3].Cells[0].Background; bg == nil || *bg != (Color{R: 255, G: 255}) {
+		t.Fatalf("行的 background 应覆盖隔行底色: %v", bg)
+	}
+	if rows[3].Cells[1].Background != nil {
+		t.Fatalf("单元格的 background none 应取消填充")
+	}
+}
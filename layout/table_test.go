@@ -0,0 +1,294 @@
+package layout
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/ByLCY/papyrus/binding"
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+func tableTexts(t TableBox) [][]string {
+	out := make([][]string, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		cells := make([]string, 0, len(row.Cells))
+		for _, cell := range row.Cells {
+			cells = append(cells, cell.Text.Content)
+		}
+		out = append(out, cells)
+	}
+	return out
+}
+
+// TestDataDrivenTable 验证 table data.items 按数组生成表头与数据行，field 表达式在每行作用域中求值。
+func TestDataDrivenTable(t *testing.T) {
+	dslText := `doc T v1 {
+  page A4 portrait margin 10mm {
+    flow {
+      table data.items width 100mm {
+        columns {
+          column 30% { header: "#"; field: loop.index }
+          column 40% { header: "描述 ${data.currency}"; field: item.desc; align: left }
+          column 30% { header: "小计"; field: formatMoney(item.price * item.qty); align: right }
+        }
+        row {
+          cell { "合计" }
+          cell { " " }
+          cell { "${formatMoney(data.total)}" }
+        }
+      }
+      table line in data.items {
+        columns {
+          column { field: "${line.desc} × ${line.qty}" }
+        }
+      }
+    }
+  }
+}`
+	data := map[string]any{
+		"currency": "CNY",
+		"total":    26.0,
+		"items": []any{
+			map[string]any{"desc": "#设计[稿]", "price": 8.0, "qty": 2.0},
+			map[string]any{"desc": "印刷", "price": 5.0, "qty": 2.0},
+		},
+	}
+	res := buildWithData(t, dslText, data)
+	tables := res.Pages[0].Tables
+	if len(tables) != 2 {
+		t.Fatalf("期望 2 个表格，实际 %d", len(tables))
+	}
+	want := [][]string{
+		{"#", "描述 CNY", "小计"},
+		{"1", "#设计[稿]", "16.00"},
+		{"2", "印刷", "10.00"},
+		{"合计", " ", "26.00"},
+	}
+	if got := tableTexts(tables[0]); !reflect.DeepEqual(got, want) {
+		t.Fatalf("表格内容错误:\n%v\n期望:\n%v", got, want)
+	}
+	if !tables[0].Rows[0].IsHeader || tables[0].Rows[1].IsHeader {
+		t.Fatalf("只有第一行应为表头")
+	}
+	if tables[0].Width != 100 || len(tables[0].ColumnWidths) != 3 {
+		t.Fatalf("表格宽度或列数错误: %v %v", tables[0].Width, tables[0].ColumnWidths)
+	}
+	if cell := tables[0].Rows[1].Cells[2].Text; cell.Align != "right" || cell.X <= tables[0].Rows[1].Cells[1].Text.X {
+		t.Fatalf("列属性应作用于单元格: %+v", cell)
+	}
+	if got := tableTexts(tables[1]); !reflect.DeepEqual(got, [][]string{{"#设计[稿] × 2"}, {"印刷 × 2"}}) {
+		t.Fatalf("自定义行变量的表格内容错误: %v", got)
+	}
+}
+
+// TestDataDrivenTableErrors 验证数据源不是数组与缺失字段的处理。
+func TestDataDrivenTableErrors(t *testing.T) {
+	dslText := `doc T v1 {
+  page A4 {
+    flow {
+      table data.items {
+        columns { column { field: item.sku } }
+      }
+    }
+  }
+}`
+	res := buildWithData(t, dslText, map[string]any{"items": []any{map[string]any{"name": "A"}}})
+	if got := res.Pages[0].Tables[0].Rows[0].Cells[0].Text.Content; got != "${item.sku}" {
+		t.Fatalf("缺失字段应按插值规则保留占位符，实际 %q", got)
+	}
+	if len(res.Unresolved) != 1 || res.Unresolved[0].Path != "item.sku" || res.Unresolved[0].Pos.Line != 5 {
+		t.Fatalf("缺失字段应记录到 Unresolved: %+v", res.Unresolved)
+	}
+
+	doc, err := dsl.Parse(strings.NewReader(dslText))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	if _, err := Build(doc, map[string]any{"items": "x"}, BuildOptions{Typesetter: &stubTypesetter{}}); err == nil {
+		t.Fatalf("数据源不是数组时应报错")
+	}
+	if _, err := Build(doc, map[string]any{"items": []any{}}, BuildOptions{Typesetter: &stubTypesetter{}, MissingBinding: binding.MissingError}); err != nil {
+		t.Fatalf("空数组不应报错: %v", err)
+	}
+}
+
+// measureTypesetter 按每个字符 2mm 计算行宽，用于验证基于内容的列宽。
+type measureTypesetter struct{}
+
+func (measureTypesetter) LayoutLines(content string, widths []float64, font FontResource, fontSize float64, lineHeight float64, wrap string) ([]TextLine, error) {
+	var lines []TextLine
+	for _, line := range strings.Split(content, "\n") {
+		lines = append(lines, TextLine{Content: line, Width: 2 * float64(utf8.RuneCountInString(line)), Height: fontSize})
+	}
+	return lines, nil
+}
+
+// TestTableColumnWidths 验证固定、百分比、auto 与 fr 列宽的计算，以及单元格按实际列宽定位。
+func TestTableColumnWidths(t *testing.T) {
+	dslText := `doc T v1 {
+  resources { font Body { src: "x.ttf" } }
+  page A4 portrait margin 10mm {
+    flow {
+      table width 100mm {
+        columns { column 20mm; column auto; column 25%; column 2fr; column * }
+        header { cell { "A" } cell { "四个字符" } cell { "C" } cell { "D" } cell { "E" } }
+        row { cell { "a" } cell { "bb" } cell { "c" } cell { "d" } cell { "e" } }
+      }
+      table width 30mm {
+        columns { column 20mm; column auto }
+        row { cell { "x" } cell { "十个字十个字十个字十" } }
+      }
+    }
+  }
+}`
+	doc, err := dsl.Parse(strings.NewReader(dslText))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	res, err := Build(doc, nil, BuildOptions{Typesetter: measureTypesetter{}})
+	if err != nil {
+		t.Fatalf("布局计算失败: %v", err)
+	}
+	tables := res.Pages[0].Tables
+	auto := 8 + 2*cellPadding
+	rest := 100 - 20 - auto - 25
+	want := []float64{20, auto, 25, rest * 2 / 3, rest / 3}
+	got := tables[0].ColumnWidths
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Fatalf("列宽错误: %v，期望 %v", got, want)
+		}
+	}
+	if math.Abs(tables[0].Width-100) > 1e-9 {
+		t.Fatalf("表格宽度应为 100mm，实际 %v", tables[0].Width)
+	}
+	for i, cell := range tables[0].Rows[1].Cells {
+		if x := tables[0].ColumnX(i) + cellPadding; math.Abs(cell.Text.X-x) > 1e-9 {
+			t.Fatalf("第 %d 列单元格 X 应为 %v，实际 %v", i, x, cell.Text.X)
+		}
+	}
+	if got := tables[1].ColumnWidths; math.Abs(got[0]-20) > 1e-9 || math.Abs(got[1]-10) > 1e-9 {
+		t.Fatalf("内容过宽时应压缩 auto 列: %v", got)
+	}
+}
+
+// TestTableSplitAcrossPages 验证长表格在行之间分页，续页顶部先输出 continued 行再重复表头。
+func TestTableSplitAcrossPages(t *testing.T) {
+	dslText := `doc T v1 {
+  page A4 portrait margin 100mm {
+    flow {
+      text { "前文" }
+      table data.items {
+        continued { cell { "（续表）" } }
+        columns {
+          column { header: "序号"; field: loop.index }
+          column { header: "名称"; field: item }
+        }
+      }
+      text { "后文" }
+    }
+  }
+}`
+	items := make([]any, 30)
+	for i := range items {
+		items[i] = fmt.Sprintf("第%d项", i+1)
+	}
+	res := buildWithData(t, dslText, map[string]any{"items": items})
+	if len(res.Pages) < 3 {
+		t.Fatalf("30 行的表格应跨越至少 3 页，实际 %d 页", len(res.Pages))
+	}
+	next := 1
+	for pi, page := range res.Pages {
+		if len(page.Tables) != 1 {
+			t.Fatalf("第 %d 页应包含一段表格，实际 %d", pi+1, len(page.Tables))
+		}
+		rows := page.Tables[0].Rows
+		if pi > 0 {
+			if !rows[0].Continued || rows[0].Cells[0].Text.Content != "（续表）" {
+				t.Fatalf("第 %d 页应以续表说明开头: %+v", pi+1, rows[0])
+			}
+			rows = rows[1:]
+		}
+		if !rows[0].IsHeader || rows[0].Cells[0].Text.Content != "序号" {
+			t.Fatalf("第 %d 页应重复表头", pi+1)
+		}
+		for _, row := range rows[1:] {
+			if got := row.Cells[0].Text.Content; got != fmt.Sprint(next) {
+				t.Fatalf("第 %d 页的行顺序错误：期望 %d，实际 %s", pi+1, next, got)
+			}
+			if row.Y+row.Height > 297-100+1e-9 {
+				t.Fatalf("第 %d 页的行超出内容区域底部: y=%v h=%v", pi+1, row.Y, row.Height)
+			}
+			if row.Cells[0].Text.Y < row.Y {
+				t.Fatalf("单元格文本应随行平移")
+			}
+			next++
+		}
+	}
+	if next != 31 {
+		t.Fatalf("应输出全部 30 行，实际 %d", next-1)
+	}
+	last := res.Pages[len(res.Pages)-1]
+	if texts := pageTexts(last); len(texts) != 1 || texts[0] != "后文" {
+		t.Fatalf("表格之后的内容应在最后一页: %v", texts)
+	}
+	if first := res.Pages[0]; first.Tables[0].Y <= first.Texts[0].Y {
+		t.Fatalf("首段表格应紧随前文而不是整体移到下一页")
+	}
+}
+
+// TestTableCellSpans 验证 colspan/rowspan 的位置分配、跨行单元格撑高末行，以及跨行的行组不在分页时拆开。
+func TestTableCellSpans(t *testing.T) {
+	dslText := `doc T v1 {
+  resources { font Body { src: "x.ttf" } }
+  page A4 portrait margin 10mm {
+    flow {
+      table width 90mm {
+        header { cell colspan 2 { "项目" } cell { "金额" } }
+        row { cell rowspan 2 { "一\n二\n三\n四\n五" } cell { "a" } cell { "1" } }
+        row { cell { "b" } cell { "2" } }
+        row { cell colspan 3 { "合计" } }
+      }
+    }
+  }
+}`
+	doc, err := dsl.Parse(strings.NewReader(dslText))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	res, err := Build(doc, nil, BuildOptions{Typesetter: measureTypesetter{}})
+	if err != nil {
+		t.Fatalf("布局计算失败: %v", err)
+	}
+	table := res.Pages[0].Tables[0]
+	if len(table.ColumnWidths) != 3 {
+		t.Fatalf("应有 3 列，实际 %d", len(table.ColumnWidths))
+	}
+	rows := table.Rows
+	head := rows[0].Cells[0]
+	if head.ColSpan != 2 || math.Abs(head.Width-60) > 1e-9 || rows[0].Cells[1].Col != 2 {
+		t.Fatalf("跨列表头的位置错误: %+v", rows[0].Cells)
+	}
+	if got := rows[2].Cells[0]; got.Col != 1 || math.Abs(got.X-table.ColumnX(1)) > 1e-9 {
+		t.Fatalf("被跨行单元格占据的位置应跳过: %+v", got)
+	}
+	span := rows[1].Cells[0]
+	if span.RowSpan != 2 || math.Abs(span.Height-(rows[1].Height+rows[2].Height)) > 1e-9 {
+		t.Fatalf("跨行单元格高度应覆盖两行: %+v", span)
+	}
+	if need := span.Text.Height + 2*cellPadding; span.Height+1e-9 < need || rows[2].Height <= rows[1].Height {
+		t.Fatalf("跨行单元格内容较高时应加高最后一行: cell=%v need=%v rows=%v/%v", span.Height, need, rows[1].Height, rows[2].Height)
+	}
+	if got := rows[3].Cells[0]; got.ColSpan != 3 || math.Abs(got.Width-table.Width) > 1e-9 {
+		t.Fatalf("跨三列单元格应占满表格宽度: %+v", got)
+	}
+
+	groups := groupTableRows(rows)
+	if len(groups) != 3 || len(groups[1]) != 2 {
+		t.Fatalf("跨行的两行应划为一组: %d 组", len(groups))
+	}
+}
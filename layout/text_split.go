@@ -0,0 +1,153 @@
+package layout
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 长文本跨页：放不下当前页剩余空间的 text 在行边界处拆成多个 TextBox，依次放在后续页面上。
+// orphans 为留在页底的最少行数，widows 为移到下一页的最少行数，默认均为 2。
+
+const defaultOrphans = 2
+const defaultWidows = 2
+
+// parseLineCount 解析 orphans/widows 的取值（正整数），无法解析时返回 def。
+func parseLineCount(value string, def int) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// linesHeight 返回 lines 的总高度；首行的 GapBefore 不计入。
+func linesHeight(lines []TextLine) float64 {
+	total := 0.0
+	for i, l := range lines {
+		if i > 0 {
+			total += l.GapBefore
+		}
+		total += l.Height
+	}
+	return total
+}
+
+// fitLines 返回高度不超过 avail 时最多能容纳的行数。
+func fitLines(lines []TextLine, avail float64) int {
+	total := 0.0
+	for i, l := range lines {
+		if i > 0 {
+			total += l.GapBefore
+		}
+		total += l.Height
+		if total > avail+1e-9 {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// splitLineCount 按 orphans/widows 调整当前页能放下的行数 n（共 total 行），返回 0 表示整段移到下一页。
+func splitLineCount(n, total, orphans, widows int) int {
+	if total-n < widows {
+		n = total - widows
+	}
+	if n < orphans {
+		return 0
+	}
+	return n
+}
+
+// splitTextBox 在第 n 行之前拆分文本框，后一部分的首行不再保留行前间距。
+func splitTextBox(tb TextBox, n int) (TextBox, TextBox) {
+	head, tail := tb, tb
+	head.Lines = append([]TextLine(nil), tb.Lines[:n]...)
+	tail.Lines = append([]TextLine(nil), tb.Lines[n:]...)
+	tail.Lines[0].GapBefore = 0
+	for _, part := range []*TextBox{&head, &tail} {
+		contents := make([]string, len(part.Lines))
+		for i, l := range part.Lines {
+			contents[i] = l.Content
+		}
+		part.Content = strings.Join(contents, "\n")
+		part.Height = linesHeight(part.Lines)
+	}
+	return head, tail
+}
+
+// placeTextBox 将文本框放到流式上下文中：当前页放不下时在行边界处拆分，剩余部分放到后续页面。
+// 不允许分页的上下文（absolute、表格单元格）中整段放置。
+// 文本框绕排浮动图片时 reflow 非空：换页前用它重新排版，使移到后续页面的行（第 n 行起）不再让出浮动区域的宽度。
+func placeTextBox(ctx *flowContext, tb TextBox, attrs map[string]string, reflow func(n int) (TextBox, error)) error {
+	place := func(tb TextBox) {
+		tb.X = ctx.baseX
+		tb.Y = ctx.cursorY
+		if acc := ctx.acc(); acc != nil {
+			acc.appendText(tb)
+		}
+		ctx.cursorY += tb.Height
+	}
+	// unindent 在前 n 行之后拆分前，去掉其余行的让出宽度
+	unindent := func(n int) error {
+		if reflow == nil || !indentedFrom(tb.Lines, n) {
+			return nil
+		}
+		next, err := reflow(n)
+		if err != nil {
+			return err
+		}
+		tb, reflow = next, nil
+		return nil
+	}
+	if !ctx.allowPageBreak || ctx.collector == nil || len(tb.Lines) < 2 {
+		if ctx.allowPageBreak && ctx.collector != nil && ctx.cursorY+tb.Height > ctx.contentBottom() {
+			if err := unindent(0); err != nil {
+				return err
+			}
+		}
+		ctx.ensureSpace(tb.Height)
+		place(tb)
+		return nil
+	}
+	orphans := parseLineCount(attrs["orphans"], defaultOrphans)
+	widows := parseLineCount(attrs["widows"], defaultWidows)
+	for {
+		avail := ctx.contentBottom() - ctx.cursorY
+		if tb.Height <= avail+1e-9 {
+			place(tb)
+			return nil
+		}
+		fit := fitLines(tb.Lines, avail)
+		n := splitLineCount(fit, len(tb.Lines), orphans, widows)
+		if n == 0 && ctx.atTop() {
+			// 整页都放不下满足约束的行数时，忽略 orphans/widows，至少放下一行
+			n = maxInt(fit, 1)
+			if n >= len(tb.Lines) {
+				place(tb)
+				return nil
+			}
+		}
+		if err := unindent(n); err != nil {
+			return err
+		}
+		if n >= len(tb.Lines) {
+			place(tb)
+			return nil
+		}
+		if n > 0 {
+			var head TextBox
+			head, tb = splitTextBox(tb, n)
+			place(head)
+		}
+		ctx.pageBreak()
+	}
+}
+
+// indentedFrom 判断第 n 行及之后是否有行为浮动区域让出了宽度。
+func indentedFrom(lines []TextLine, n int) bool {
+	for _, l := range lines[minInt(n, len(lines)):] {
+		if l.Indent != 0 || l.RightIndent != 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package layout
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+// newlineTypesetter 仅在显式换行处分行，便于精确控制行数。
+type newlineTypesetter struct{}
+
+func (newlineTypesetter) LayoutLines(content string, widths []float64, font FontResource, fontSize float64, lineHeight float64, wrap string) ([]TextLine, error) {
+	var out []TextLine
+	for _, l := range strings.Split(content, "\n") {
+		out = append(out, TextLine{Content: l, Height: fontSize, GapBefore: lineHeight - fontSize})
+	}
+	return out, nil
+}
+
+// TestTextSplitAcrossPages 验证长文本在行边界处跨页拆分，并遵守 orphans/widows。
+func TestTextSplitAcrossPages(t *testing.T) {
+	lines := func(prefix string, n int) string {
+		parts := make([]string, n)
+		for i := range parts {
+			parts[i] = prefix + string(rune('a'+i))
+		}
+		return strings.Join(parts, `\n`)
+	}
+	filler := `text Line { "` + lines("f", 11) + `" }`
+	cases := []struct {
+		name  string
+		body  string
+		pages []int // 每页中段落（不含 filler）的行数
+	}{
+		{"整页以上", `text Line { "` + lines("p", 30) + `" }`, []int{13, 13, 4}},
+		{"默认拆分", filler + "\n" + `text Line { "` + lines("p", 5) + `" }`, []int{2, 3}},
+		{"orphans", filler + "\n" + `text Line orphans 3 { "` + lines("p", 5) + `" }`, []int{0, 5}},
+		{"widows", filler + "\n" + `text Line { "` + lines("p", 3) + `" }`, []int{0, 3}},
+		{"widows 1", filler + "\n" + `text Line widows 1 { "` + lines("p", 3) + `" }`, []int{2, 1}},
+	}
+	for _, tc := range cases {
+		dslText := `doc T v1 {
+  resources {
+    font Body { src: "x.ttf" }
+    style Line { font: Body; size: 20mm; line-height: 1x }
+  }
+  page A4 portrait margin 10mm {
+    flow {
+      ` + tc.body + `
+    }
+  }
+}`
+		doc, err := dsl.Parse(strings.NewReader(dslText))
+		if err != nil {
+			t.Fatalf("%s: 解析 DSL 失败: %v", tc.name, err)
+		}
+		res, err := Build(doc, nil, BuildOptions{Typesetter: newlineTypesetter{}})
+		if err != nil {
+			t.Fatalf("%s: 布局计算失败: %v", tc.name, err)
+		}
+		var got []int
+		for _, p := range res.Pages {
+			n := 0
+			for _, tb := range p.Texts {
+				if strings.HasPrefix(tb.Content, "p") {
+					n += len(tb.Lines)
+					if !eq(tb.Height, float64(len(tb.Lines))*20) {
+						t.Fatalf("%s: 拆分后的高度不符: %.2f", tc.name, tb.Height)
+					}
+					if tb.Y+tb.Height > 287+1e-6 {
+						t.Fatalf("%s: 文本超出内容区域底部: y=%.2f h=%.2f", tc.name, tb.Y, tb.Height)
+					}
+				}
+			}
+			got = append(got, n)
+		}
+		if !reflect.DeepEqual(got, tc.pages) {
+			t.Fatalf("%s: 各页行数不符: got %v, want %v", tc.name, got, tc.pages)
+		}
+		if len(res.Pages) > 1 && !eq(res.Pages[1].Texts[0].Y, 10) {
+			t.Fatalf("%s: 续页应从内容区域顶部开始，got %.2f", tc.name, res.Pages[1].Texts[0].Y)
+		}
+	}
+}
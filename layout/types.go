@@ -1,12 +1,15 @@
 package layout
 
+import "github.com/ByLCY/papyrus/binding"
+
 // 该文件定义布局结果与资源描述，供布局计算、渲染与调试 JSON 共用。
 
 // Result 保存布局后的页面与资源信息。
 type Result struct {
-	Pages     []Page       `json:"pages"`
-	Resources ResourceSet  `json:"resources"`
-	Meta      DocumentMeta `json:"meta"`
+	Pages      []Page               `json:"pages"`
+	Resources  ResourceSet          `json:"resources"`
+	Meta       DocumentMeta         `json:"meta"`
+	Unresolved []binding.Unresolved `json:"unresolved,omitempty"` // 未能解析的 ${...} 绑定（含源码位置）
 }
 
 // ResourceSet 记录解析出的字体、颜色与图片定义。
@@ -15,6 +18,8 @@ type ResourceSet struct {
 	Colors map[string]Color         `json:"colors"`
 	Images map[string]ImageResource `json:"images"`
 	Styles map[string]Style         `json:"styles"`
+	// BlockSpacing 为块与块之间的默认间距（mm），由 resources 中的 block-spacing 指定
+	BlockSpacing float64 `json:"blockSpacing"`
 }
 
 // FontResource 描述字体资源，src 可以是文件路径、内置 embed 路径或 builtin:* 形式。
@@ -97,20 +102,23 @@ type TextBox struct {
 	Debug      *TextBoxDebug `json:"debug,omitempty"`
 }
 
-// TextLine 表示排版后的一行文本内容及其宽高。
+// TextSpan 表示一行文本中带行内格式（如下划线）的片段。
 type TextSpan struct {
-	Start     int   `json:"start"`              // 起始位置（以 rune 计数）
-	Length    int   `json:"length"`             // 跨度长度（以 rune 计数）
-	Underline bool  `json:"underline,omitempty"` // 是否绘制下划线
+	Start     int  `json:"start"`               // 起始位置（以 rune 计数）
+	Length    int  `json:"length"`              // 跨度长度（以 rune 计数）
+	Underline bool `json:"underline,omitempty"` // 是否绘制下划线
 }
 
 // TextLine 表示排版后的一行文本内容及其宽高。
 type TextLine struct {
-	Content   string    `json:"content"`
-	Width     float64   `json:"width"`
-	Height    float64   `json:"height"`
-	GapBefore float64   `json:"gapBefore,omitempty"`
+	Content   string     `json:"content"`
+	Width     float64    `json:"width"`
+	Height    float64    `json:"height"`
+	GapBefore float64    `json:"gapBefore,omitempty"`
 	Spans     []TextSpan `json:"spans,omitempty"`
+	// Indent/RightIndent 为该行左右两侧让出的宽度（文字绕排浮动图片），行的可用宽度为文本框宽度减去两者。
+	Indent      float64 `json:"indent,omitempty"`
+	RightIndent float64 `json:"rightIndent,omitempty"`
 }
 
 // TextBoxDebug holds optional debug info displayed only when enabled by BuildOptions.
@@ -149,7 +157,7 @@ type ImageBox struct {
 	Opacity float64 `json:"opacity"`
 }
 
-// TableBox 保存简化表格布局信息（平均列宽）。
+// TableBox 保存表格布局信息，Width 为各列宽度之和。
 type TableBox struct {
 	X            float64    `json:"x"`
 	Y            float64    `json:"y"`
@@ -160,17 +168,69 @@ type TableBox struct {
 	BorderColor  Color      `json:"borderColor"`
 }
 
+// ColumnX 返回第 i 列左边界的 X 坐标；i 等于列数时返回表格右边界。
+func (t TableBox) ColumnX(i int) float64 {
+	x := t.X
+	for j := 0; j < i && j < len(t.ColumnWidths); j++ {
+		x += t.ColumnWidths[j]
+	}
+	return x
+}
+
 // TableRow 记录每一行的高度与单元格。
+// 表格跨页时，开头的表头行会在续页顶部重复（IsHeader），continued 声明的续表说明行只出现在续页（Continued）；
+// footer 声明的合计行与分页小计标记为 IsFooter。
 type TableRow struct {
-	Y        float64     `json:"y"`
-	Height   float64     `json:"height"`
-	IsHeader bool        `json:"isHeader"`
-	Cells    []TableCell `json:"cells"`
+	Y          float64     `json:"y"`
+	Height     float64     `json:"height"`
+	IsHeader   bool        `json:"isHeader"`
+	Continued  bool        `json:"continued,omitempty"`
+	IsFooter   bool        `json:"isFooter,omitempty"`
+	Background *Color      `json:"background,omitempty"` // 行的底色，用于绘制行中没有单元格的位置
+	Border     Borders     `json:"border"`               // 行的边框，用途同上
+	Cells      []TableCell `json:"cells"`
 }
 
-// TableCell 复用 TextBox 作为单元格内容。
+// TableCell 复用 TextBox 作为单元格内容，X/Y/Width/Height 为单元格边框的位置与尺寸。
+// 跨行单元格属于其起始行，高度覆盖所跨的各行（含行距）。包含命令的单元格使用 Content 而不是 Text。
 type TableCell struct {
-	Text TextBox `json:"text"`
+	X          float64      `json:"x"`
+	Y          float64      `json:"y"`
+	Width      float64      `json:"width"`
+	Height     float64      `json:"height"`
+	Col        int          `json:"col"`                  // 起始列序号（从 0 开始）
+	ColSpan    int          `json:"colSpan"`              // 跨越的列数
+	RowSpan    int          `json:"rowSpan"`              // 跨越的行数
+	Background *Color       `json:"background,omitempty"` // 为空表示不填充
+	Border     Borders      `json:"border"`
+	Padding    Margin       `json:"padding"`
+	VAlign     string       `json:"valign"` // top、middle 或 bottom，已体现在内容的纵坐标中
+	Text       TextBox      `json:"text"`
+	Content    *CellContent `json:"content,omitempty"`
+}
+
+// CellContent 是富内容单元格中已定位的元素（页面坐标，单位 mm），与页面上的同名字段含义相同。
+type CellContent struct {
+	Texts   []TextBox  `json:"texts,omitempty"`
+	Images  []ImageBox `json:"images,omitempty"`
+	Tables  []TableBox `json:"tables,omitempty"`
+	Lines   []Line     `json:"lines,omitempty"`
+	Rects   []Rect     `json:"rects,omitempty"`
+	Circles []Circle   `json:"circles,omitempty"`
+}
+
+// Border 是一条边框线，Width 为 0 表示不绘制。
+type Border struct {
+	Width float64 `json:"width"` // mm
+	Color Color   `json:"color"`
+}
+
+// Borders 是矩形四边的边框。
+type Borders struct {
+	Top    Border `json:"top"`
+	Right  Border `json:"right"`
+	Bottom Border `json:"bottom"`
+	Left   Border `json:"left"`
 }
 
 // 基本图形：直线、矩形、圆形（单位均为 mm）。
@@ -193,6 +253,8 @@ type Rect struct {
 	StrokeColor Color   `json:"strokeColor"`
 	StrokeWidth float64 `json:"strokeWidth"`         // mm
 	FillColor   *Color  `json:"fillColor,omitempty"` // 为空表示不填充
+	NoStroke    bool    `json:"noStroke,omitempty"`  // 不绘制边框（如仅有背景的 flow）
+	Radius      float64 `json:"radius,omitempty"`    // 圆角半径（mm）
 }
 
 // Circle 表示一个圆。
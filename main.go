@@ -8,20 +8,36 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/ByLCY/papyrus/binding"
 	"github.com/ByLCY/papyrus/dsl"
 	"github.com/ByLCY/papyrus/layout"
 	"github.com/ByLCY/papyrus/renderer"
 	canvasrenderer "github.com/ByLCY/papyrus/renderer/canvas"
+	"github.com/ByLCY/papyrus/schema"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		if err := runSchema(os.Args[2:]); err != nil {
+			log.Fatalf("生成 Schema 失败: %v", err)
+		}
+		return
+	}
+
 	input := flag.String("in", "examples/demo.papyrus", "DSL 文件路径")
 	output := flag.String("out", "output/demo.pdf", "PDF 输出路径")
 	debug := flag.String("debug", "", "布局调试 JSON 输出路径")
 	debugRawUnits := flag.Bool("debug-raw-units", false, "在调试 JSON 中输出 debug.rawUnits 影子字段")
 	dataJSON := flag.String("data", "", "绑定到 DSL 的 JSON 数据")
+	missing := flag.String("missing", "keep", "数据路径不存在时的处理方式：keep（保留占位符并警告）、empty（输出空字符串并警告）、error（构建失败）")
+	schemaPath := flag.String("schema", "", "用于校验 data 的 JSON Schema 文件路径")
 	flag.Parse()
 
+	policy, err := binding.ParseMissingPolicy(*missing)
+	if err != nil {
+		log.Fatalf("参数错误: %v", err)
+	}
+
 	var inputData any
 	if *dataJSON != "" {
 		if err := json.Unmarshal([]byte(*dataJSON), &inputData); err != nil {
@@ -29,15 +45,32 @@ func main() {
 		}
 	}
 
+	var dataSchema *schema.Schema
+	if *schemaPath != "" {
+		raw, err := os.ReadFile(*schemaPath)
+		if err != nil {
+			log.Fatalf("读取 Schema 文件失败: %v", err)
+		}
+		if dataSchema, err = schema.Parse(raw); err != nil {
+			log.Fatalf("%s: %v", *schemaPath, err)
+		}
+	}
+
 	var r renderer.Renderer = canvasrenderer.NewRenderer(filepath.Dir(*input))
-	if err := run(*input, *output, *debug, *debugRawUnits, inputData, r); err != nil {
+	if err := run(*input, *output, *debug, *debugRawUnits, inputData, buildConfig{missing: policy, schema: dataSchema}, r); err != nil {
 		log.Fatalf("生成 PDF 失败: %v", err)
 	}
 	fmt.Printf("已生成 PDF：%s\n", *output)
 }
 
+// buildConfig 汇总影响数据绑定的命令行参数。
+type buildConfig struct {
+	missing binding.MissingPolicy // keep/empty 时未解析的绑定以警告形式输出到标准错误；error 时构建失败
+	schema  *schema.Schema        // 非空时在布局前校验数据
+}
+
 // run 串联解析、布局与渲染。
-func run(inputPath, outputPath, debugPath string, debugRawUnits bool, data any, r renderer.Renderer) error {
+func run(inputPath, outputPath, debugPath string, debugRawUnits bool, data any, cfg buildConfig, r renderer.Renderer) error {
 	if r == nil {
 		return fmt.Errorf("renderer 不能为空")
 	}
@@ -58,12 +91,17 @@ func run(inputPath, outputPath, debugPath string, debugRawUnits bool, data any,
 	}
 
 	result, err := layout.Build(doc, data, layout.BuildOptions{
-		Typesetter: ts,
-		Debug:      layout.DebugOptions{RawUnits: debugRawUnits},
+		Typesetter:     ts,
+		Debug:          layout.DebugOptions{RawUnits: debugRawUnits},
+		MissingBinding: cfg.missing,
+		Schema:         cfg.schema,
 	})
 	if err != nil {
 		return fmt.Errorf("布局计算失败: %w", err)
 	}
+	for _, u := range result.Unresolved {
+		fmt.Fprintf(os.Stderr, "警告：%s:%s\n", inputPath, u)
+	}
 
 	if debugPath != "" {
 		if err := writeDebug(result, debugPath); err != nil {
@@ -86,6 +124,51 @@ func run(inputPath, outputPath, debugPath string, debugRawUnits bool, data any,
 	return nil
 }
 
+// runSchema 实现 `papyrus schema` 子命令：推断 DSL 所需数据的 JSON Schema，-example 时输出示例数据骨架。
+func runSchema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	input := fs.String("in", "examples/demo.papyrus", "DSL 文件路径")
+	output := fs.String("out", "", "输出路径，留空时输出到标准输出")
+	example := fs.Bool("example", false, "输出示例数据骨架而非 JSON Schema")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	file, err := os.Open(*input)
+	if err != nil {
+		return fmt.Errorf("无法打开 DSL 文件 %s: %w", *input, err)
+	}
+	defer file.Close()
+
+	doc, err := dsl.Parse(file)
+	if err != nil {
+		return fmt.Errorf("解析 DSL 失败: %w", err)
+	}
+	s, err := schema.Infer(doc)
+	if err != nil {
+		return fmt.Errorf("%s:%w", *input, err)
+	}
+
+	var out []byte
+	if *example {
+		out, err = json.MarshalIndent(s.Example(), "", "  ")
+	} else {
+		out, err = s.MarshalIndent()
+	}
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+	if *output == "" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(*output), 0o755); err != nil {
+		return fmt.Errorf("创建输出目录失败: %w", err)
+	}
+	return os.WriteFile(*output, out, 0o644)
+}
+
 func writeDebug(result *layout.Result, debugPath string) error {
 	if err := os.MkdirAll(filepath.Dir(debugPath), 0o755); err != nil {
 		return fmt.Errorf("创建调试目录失败: %w", err)
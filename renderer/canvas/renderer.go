@@ -151,9 +151,9 @@ func (r *Renderer) applyMeta(writer *pdf.PDF, meta layout.DocumentMeta) {
 	writer.SetInfo(meta.Title, meta.Subject, keywords, meta.Author, meta.Creator)
 }
 
-// LayoutLines 实现 layout.Typesetter 接口，使用贪心换行算法。
+// LayoutLines 实现 layout.Typesetter 接口，使用贪心换行算法，第 i 行按 layout.LineWidth(widths, i) 折行。
 // 约定：fontSize/lineHeight 入参均为毫米（mm）。渲染器内部与字体系统交互使用 pt，并在边界做 mm↔pt 换算。
-func (r *Renderer) LayoutLines(content string, width float64, font layout.FontResource, fontSize, lineHeight float64, wrap string) ([]layout.TextLine, error) {
+func (r *Renderer) LayoutLines(content string, widths []float64, font layout.FontResource, fontSize, lineHeight float64, wrap string) ([]layout.TextLine, error) {
 	// 将字号从 mm 转为 pt 以创建字体面
 	sizePt := toPt(fontSize)
 	face, err := r.fontFace(font, sizePt, layout.Color{R: 30, G: 30, B: 30})
@@ -165,7 +165,7 @@ func (r *Renderer) LayoutLines(content string, width float64, font layout.FontRe
 	if wrap == "" {
 		wrap = "anywhere"
 	}
-	lines := greedyWrapTokens(content, width, face, wrap)
+	lines := greedyWrapTokens(content, widths, face, wrap)
 	textMetrics := face.Metrics()
 	textHeight := textMetrics.LineHeight
 	if textHeight <= 0 {
@@ -278,24 +278,29 @@ func (r *Renderer) drawTextBox(ctx *canvas.Context, tb layout.TextBox, fontRes l
 		}
 	}
 
-	// 处理水平对齐：left（默认）/center/right。
+	// 处理水平对齐：left（默认）/center/right；各行在让出 Indent/RightIndent 后的可用宽度内对齐。
 	align := strings.ToLower(tb.Align)
 	var textAlign canvas.TextAlign
-	var anchorX float64
 	switch align {
 	case "center":
 		textAlign = canvas.Center
-		anchorX = tb.X + tb.Width/2
 	case "right", "end":
 		textAlign = canvas.Right
-		anchorX = tb.X + tb.Width
 	default:
 		textAlign = canvas.Left
-		anchorX = tb.X
 	}
 
 	cursorY := tb.Y
 	for _, line := range lines {
+		left := tb.X + line.Indent
+		available := tb.Width - line.Indent - line.RightIndent
+		anchorX := left
+		switch textAlign {
+		case canvas.Center:
+			anchorX = left + available/2
+		case canvas.Right:
+			anchorX = left + available
+		}
 		cursorY += line.GapBefore
 		textLine := canvas.NewTextLine(face, line.Content, textAlign)
 
@@ -432,26 +437,31 @@ func (r *Renderer) drawImages(ctx *canvas.Context, images []layout.ImageBox) err
 
 func (r *Renderer) drawTables(ctx *canvas.Context, tables []layout.TableBox, fonts map[string]layout.FontResource) error {
 	for _, table := range tables {
-		if len(table.ColumnWidths) == 0 {
+		cols := len(table.ColumnWidths)
+		if cols == 0 {
 			continue
 		}
-		for _, row := range table.Rows {
-			x := table.X
-			for idx, cell := range row.Cells {
-				colIdx := idx
-				if colIdx >= len(table.ColumnWidths) {
-					colIdx = len(table.ColumnWidths) - 1
+		// 先绘制所有单元格的底色与边框，再绘制文本，避免后续行的底色覆盖跨行单元格的文本。
+		// 每个单元格按自身范围绘制，跨行/跨列单元格内部的网格线因此不会绘制；没有单元格的位置按行的外观补画。
+		covered := tableCoverage(table)
+		for r, row := range table.Rows {
+			for c := 0; c < cols; c++ {
+				if !covered[r][c] {
+					drawCellBox(ctx, table.ColumnX(c), row.Y, table.ColumnWidths[c], row.Height, row.Background, row.Border)
 				}
-				colWidth := table.ColumnWidths[colIdx]
-				fill := canvas.White
-				if row.IsHeader {
-					fill = canvas.Hex("#f8f8f8")
+			}
+			for _, cell := range row.Cells {
+				drawCellBox(ctx, cell.X, cell.Y, cell.Width, cell.Height, cell.Background, cell.Border)
+			}
+		}
+		for _, row := range table.Rows {
+			for _, cell := range row.Cells {
+				if cell.Content != nil {
+					if err := r.drawCellContent(ctx, cell.Content, fonts); err != nil {
+						return err
+					}
+					continue
 				}
-				ctx.SetFillColor(fill)
-				ctx.SetStrokeColor(colorFromLayout(table.BorderColor))
-				ctx.SetStrokeWidth(tableBorderWidth)
-				ctx.DrawPath(x, row.Y, canvas.Rectangle(colWidth, row.Height))
-
 				fontRes := resolveFontResource(cell.Text.Font, fonts)
 				textBox := cell.Text
 				textBox.X += tableBorderWidth
@@ -459,13 +469,83 @@ func (r *Renderer) drawTables(ctx *canvas.Context, tables []layout.TableBox, fon
 				if err := r.drawTextBox(ctx, textBox, fontRes); err != nil {
 					return err
 				}
-				x += colWidth
 			}
 		}
 	}
 	return nil
 }
 
+// drawCellContent 绘制富内容单元格中的元素：先形状，再文本、图片与嵌套表格，顺序与页面主体一致。
+func (r *Renderer) drawCellContent(ctx *canvas.Context, content *layout.CellContent, fonts map[string]layout.FontResource) error {
+	if err := r.drawLines(ctx, content.Lines); err != nil {
+		return err
+	}
+	if err := r.drawRects(ctx, content.Rects); err != nil {
+		return err
+	}
+	if err := r.drawCircles(ctx, content.Circles); err != nil {
+		return err
+	}
+	for _, tb := range content.Texts {
+		if err := r.drawTextBox(ctx, tb, resolveFontResource(tb.Font, fonts)); err != nil {
+			return err
+		}
+	}
+	if err := r.drawImages(ctx, content.Images); err != nil {
+		return err
+	}
+	return r.drawTables(ctx, content.Tables, fonts)
+}
+
+// drawCellBox 绘制单元格底色（bg 为空时不填充）与四条边框，宽度为 0 的边不绘制。
+func drawCellBox(ctx *canvas.Context, x, y, w, h float64, bg *layout.Color, border layout.Borders) {
+	if bg != nil {
+		ctx.SetFillColor(colorFromLayout(*bg))
+		ctx.SetStrokeColor(color.RGBA{0, 0, 0, 0})
+		ctx.DrawPath(x, y, canvas.Rectangle(w, h))
+	}
+	ctx.SetFillColor(color.RGBA{0, 0, 0, 0})
+	sides := []struct {
+		b              layout.Border
+		x1, y1, x2, y2 float64
+	}{
+		{border.Top, x, y, x + w, y},
+		{border.Right, x + w, y, x + w, y + h},
+		{border.Bottom, x, y + h, x + w, y + h},
+		{border.Left, x, y, x, y + h},
+	}
+	for _, side := range sides {
+		if side.b.Width <= 0 {
+			continue
+		}
+		ctx.SetStrokeColor(colorFromLayout(side.b.Color))
+		ctx.SetStrokeWidth(side.b.Width)
+		p := &canvas.Path{}
+		p.MoveTo(0, 0)
+		p.LineTo(side.x2-side.x1, side.y2-side.y1)
+		ctx.DrawPath(side.x1, side.y1, p)
+	}
+}
+
+// tableCoverage 标记表格片段中每一行的各列是否被单元格（含上方的跨行单元格）覆盖。
+func tableCoverage(table layout.TableBox) [][]bool {
+	cols := len(table.ColumnWidths)
+	covered := make([][]bool, len(table.Rows))
+	for r := range covered {
+		covered[r] = make([]bool, cols)
+	}
+	for r, row := range table.Rows {
+		for _, cell := range row.Cells {
+			for dr := 0; dr < max(cell.RowSpan, 1) && r+dr < len(table.Rows); dr++ {
+				for dc := 0; dc < max(cell.ColSpan, 1) && cell.Col+dc < cols; dc++ {
+					covered[r+dr][cell.Col+dc] = true
+				}
+			}
+		}
+	}
+	return covered
+}
+
 // drawLines 绘制直线列表（毫米单位）
 func (r *Renderer) drawLines(ctx *canvas.Context, lines []layout.Line) error {
 	for _, ln := range lines {
@@ -495,9 +575,17 @@ func (r *Renderer) drawRects(ctx *canvas.Context, rects []layout.Rect) error {
 		} else {
 			ctx.SetFillColor(color.RGBA{0, 0, 0, 0})
 		}
-		ctx.SetStrokeColor(colorFromLayout(rc.StrokeColor))
+		if rc.NoStroke {
+			ctx.SetStrokeColor(color.RGBA{0, 0, 0, 0})
+		} else {
+			ctx.SetStrokeColor(colorFromLayout(rc.StrokeColor))
+		}
 		ctx.SetStrokeWidth(w)
-		ctx.DrawPath(rc.X, rc.Y, canvas.Rectangle(rc.Width, rc.Height))
+		path := canvas.Rectangle(rc.Width, rc.Height)
+		if rc.Radius > 0 {
+			path = canvas.RoundedRectangle(rc.Width, rc.Height, rc.Radius)
+		}
+		ctx.DrawPath(rc.X, rc.Y, path)
 	}
 	return nil
 }
@@ -670,17 +758,25 @@ func toPt(mm float64) float64 { return mm * layout.MmToPt }
 // toMm 将点(pt)转换为毫米(mm)。
 func toMm(pt float64) float64 { return pt * layout.PtToMm }
 
-func greedyWrapTokens(content string, width float64, face *canvas.FontFace, wrap string) []layout.TextLine {
+// textMeasurer 测量文本宽度，由 *canvas.FontFace 实现；折行逻辑只依赖该接口，测试中可替换为不依赖字体文件的实现。
+type textMeasurer interface {
+	TextWidth(s string) float64
+}
+
+func greedyWrapTokens(content string, widths []float64, face textMeasurer, wrap string) []layout.TextLine {
 	// 说明：本函数内部的所有宽度单位在逻辑上按 mm 处理；canvas 的 TextWidth 返回的值已在现有实现中用于与 width 比较，保持现状避免破坏兼容。
-	limit := width
-	if limit <= 0 {
-		limit = math.MaxFloat64
+	var lines []layout.TextLine
+	// limit 返回正在填充的行（第 len(lines) 行）的宽度限制
+	limit := func() float64 {
+		if w := layout.LineWidth(widths, len(lines)); w > 0 {
+			return w
+		}
+		return math.MaxFloat64
 	}
 
 	// nowrap：仅按显式换行划分，不基于宽度折行
 	if wrap == "nowrap" {
 		parts := strings.Split(content, "\n")
-		lines := make([]layout.TextLine, 0, len(parts))
 		for _, p := range parts {
 			w := face.TextWidth(p)
 			lines = append(lines, layout.TextLine{Content: p, Width: w})
@@ -690,7 +786,6 @@ func greedyWrapTokens(content string, width float64, face *canvas.FontFace, wrap
 
 	// break-word：忽略空白机会，纯按宽度切分（但仍然尊重显式换行）
 	if wrap == "break-word" {
-		var lines []layout.TextLine
 		var builder strings.Builder
 		current := 0.0
 		emit := func(force bool) {
@@ -715,12 +810,12 @@ func greedyWrapTokens(content string, width float64, face *canvas.FontFace, wrap
 			}
 			s := string(r)
 			cw := face.TextWidth(s)
-			if current > 0 && current+cw > limit {
+			if current > 0 && current+cw > limit() {
 				emit(false)
 			}
 			builder.WriteString(s)
 			current += cw
-			if current > limit {
+			if current > limit() {
 				emit(false)
 			}
 		}
@@ -730,7 +825,6 @@ func greedyWrapTokens(content string, width float64, face *canvas.FontFace, wrap
 
 	// 默认（anywhere/normal 等）：优先在空白处分割，超过限制时在词内拆分
 	tokens := tokenizeContent(content)
-	var lines []layout.TextLine
 	var builder strings.Builder
 	currentWidth := 0.0
 
@@ -762,24 +856,26 @@ func greedyWrapTokens(content string, width float64, face *canvas.FontFace, wrap
 		}
 
 		tokenWidth := face.TextWidth(token)
-		if currentWidth > 0 && currentWidth+tokenWidth > limit {
+		if currentWidth > 0 && currentWidth+tokenWidth > limit() {
 			emit(false)
 		}
-		if tokenWidth <= limit {
+		if tokenWidth <= limit() {
 			appendToken(token)
-			if currentWidth > limit {
+			if currentWidth > limit() {
 				emit(false)
 			}
 			continue
 		}
 
-		for _, chunk := range splitTokenByWidth(token, limit, face) {
-			chunkWidth := face.TextWidth(chunk)
-			if currentWidth > 0 && currentWidth+chunkWidth > limit {
+		// 各行宽度可能不同，每次只取按当前行宽度拆出的第一段；前一段已占满一行，先换行再取下一段
+		for rest := token; rest != ""; {
+			if currentWidth > 0 {
 				emit(false)
 			}
+			chunk := firstChunkByWidth(rest, limit(), face)
+			rest = rest[len(chunk):]
 			appendToken(chunk)
-			if currentWidth > limit {
+			if currentWidth > limit() {
 				emit(false)
 			}
 		}
@@ -824,24 +920,24 @@ func tokenizeContent(s string) []string {
 	return tokens
 }
 
-func splitTokenByWidth(token string, limit float64, face *canvas.FontFace) []string {
-	// 说明：limit 为 mm，需要将 canvas 返回的宽度（pt）转换为 mm 后再比较
+// firstChunkByWidth 返回 token 开头不超过 limit 的最长片段（至少一个字符）；超出 limit 后即停止测量，
+// 长词逐行拆分时只测量当前行放得下的部分。
+func firstChunkByWidth(token string, limit float64, face textMeasurer) string {
 	if limit <= 0 || limit == math.MaxFloat64 {
-		return []string{token}
+		return token
 	}
-	var parts []string
-	var builder strings.Builder
-	for _, r := range token {
-		builder.WriteRune(r)
-		if face.TextWidth(builder.String()) > limit && builder.Len() > 1 {
-			runes := []rune(builder.String())
-			parts = append(parts, string(runes[:len(runes)-1]))
-			builder.Reset()
-			builder.WriteRune(r)
+	end := 0 // 已确认放得下的前缀长度（字节）
+	for i := range token {
+		if i == 0 {
+			continue
+		}
+		if end > 0 && face.TextWidth(token[:i]) > limit {
+			return token[:end]
 		}
+		end = i
 	}
-	if builder.Len() > 0 {
-		parts = append(parts, builder.String())
+	if end > 0 && face.TextWidth(token) > limit {
+		return token[:end]
 	}
-	return parts
+	return token
 }
@@ -15,7 +15,7 @@ func TestNoBlankLineWhenEqualWidthThenNewline(t *testing.T) {
 
 	first := "SAMPLE-A"
 	// 用极大宽度先测量第一行宽度（mm）
-	measured, err := r.LayoutLines(first, 1e6, font, fontSizeMM, lineHeightMM, "")
+	measured, err := r.LayoutLines(first, []float64{1e6}, font, fontSizeMM, lineHeightMM, "")
 	if err != nil {
 		t.Fatalf("measure error: %v", err)
 	}
@@ -29,7 +29,7 @@ func TestNoBlankLineWhenEqualWidthThenNewline(t *testing.T) {
 
 	// 构造恰好等宽 + 显式换行 + 下一行内容
 	content := first + "\n" + "SAMPLE-B"
-	lines, err := r.LayoutLines(content, limit, font, fontSizeMM, lineHeightMM, "")
+	lines, err := r.LayoutLines(content, []float64{limit}, font, fontSizeMM, lineHeightMM, "")
 	if err != nil {
 		t.Fatalf("LayoutLines error: %v", err)
 	}
@@ -2,6 +2,7 @@ package canvasrenderer
 
 import (
 	"math"
+	"strings"
 	"testing"
 
 	"github.com/ByLCY/papyrus/layout"
@@ -18,7 +19,7 @@ func TestLayoutLinesGreedyWrapsText(t *testing.T) {
 	fontSizeMM := 12 * layout.PtToMm
 	lineHeightMM := fontSizeMM * 1.2
 
-	lines, err := r.LayoutLines("hello world again", 10, font, fontSizeMM, lineHeightMM, "")
+	lines, err := r.LayoutLines("hello world again", []float64{10}, font, fontSizeMM, lineHeightMM, "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -27,6 +28,29 @@ func TestLayoutLinesGreedyWrapsText(t *testing.T) {
 	}
 }
 
+// TestLayoutLinesPerLineWidths 验证各行按各自的可用宽度折行，超出 widths 的行沿用最后一个值。
+func TestLayoutLinesPerLineWidths(t *testing.T) {
+	r := NewRenderer(".")
+	font := layout.FontResource{
+		Name: "Body",
+		Src:  "embed:Inter/static/Inter-Regular.ttf",
+	}
+
+	fontSizeMM := 12 * layout.PtToMm
+	lineHeightMM := fontSizeMM * 1.2
+
+	lines, err := r.LayoutLines("hello world again here", []float64{15, 200}, font, fontSizeMM, lineHeightMM, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %+v", len(lines), lines)
+	}
+	if got := strings.TrimSpace(lines[0].Content); got != "hello" {
+		t.Fatalf("expected first line to hold only %q, got %q", "hello", got)
+	}
+}
+
 func TestGreedyWrapHonorsNewlines(t *testing.T) {
 	r := NewRenderer(".")
 	font := layout.FontResource{
@@ -37,7 +61,7 @@ func TestGreedyWrapHonorsNewlines(t *testing.T) {
 	fontSizeMM := 12 * layout.PtToMm
 	lineHeightMM := fontSizeMM * 1.2
 
-	lines, err := r.LayoutLines("foo\n\nbar", 100, font, fontSizeMM, lineHeightMM, "")
+	lines, err := r.LayoutLines("foo\n\nbar", []float64{100}, font, fontSizeMM, lineHeightMM, "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -63,7 +87,7 @@ func TestLineHeightsInvariant(t *testing.T) {
 	lineHeightMM := fontSizeMM * 1.3
 
 	content := "longlonglong longlonglong longlonglong longlonglong longlonglong"
-	lines, err := r.LayoutLines(content, 40, font, fontSizeMM, lineHeightMM, "")
+	lines, err := r.LayoutLines(content, []float64{40}, font, fontSizeMM, lineHeightMM, "")
 	if err != nil {
 		t.Fatalf("LayoutLines error: %v", err)
 	}
@@ -101,7 +125,7 @@ func TestGreedyWrapWidthLimit(t *testing.T) {
 
 	limit := 30.0 // mm
 	content := "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
-	lines, err := r.LayoutLines(content, limit, font, fontSizeMM, lineHeightMM, "")
+	lines, err := r.LayoutLines(content, []float64{limit}, font, fontSizeMM, lineHeightMM, "")
 	if err != nil {
 		t.Fatalf("LayoutLines error: %v", err)
 	}
@@ -114,3 +138,77 @@ func TestGreedyWrapWidthLimit(t *testing.T) {
 		}
 	}
 }
+
+// TestGreedyWrapLongTokenPerLineWidths 验证长词按各行的宽度逐段拆分，拼接后与原文一致。
+func TestGreedyWrapLongTokenPerLineWidths(t *testing.T) {
+	r := NewRenderer(".")
+	font := layout.FontResource{Src: "embed:Inter/static/Inter-Regular.ttf"}
+	fontSizeMM := 12 * layout.PtToMm
+	lineHeightMM := fontSizeMM * 1.2
+
+	widths := []float64{10, 40}
+	content := strings.Repeat("https://example.com/", 10)
+	lines, err := r.LayoutLines(content, widths, font, fontSizeMM, lineHeightMM, "")
+	if err != nil {
+		t.Fatalf("LayoutLines error: %v", err)
+	}
+	var joined strings.Builder
+	for i, ln := range lines {
+		if limit := layout.LineWidth(widths, i); ln.Width-limit > 1e-6 {
+			t.Fatalf("line %d width exceeds limit: width=%g limit=%g", i, ln.Width, limit)
+		}
+		joined.WriteString(ln.Content)
+	}
+	if joined.String() != content {
+		t.Fatalf("lines should join back to the token, got %q", joined.String())
+	}
+}
+
+// runeMeasurer 把每个字符视为 1mm 宽，并记录测量过的最长文本，用于不依赖字体文件的折行测试。
+type runeMeasurer struct {
+	longest int
+}
+
+func (m *runeMeasurer) TextWidth(s string) float64 {
+	n := len([]rune(s))
+	if n > m.longest {
+		m.longest = n
+	}
+	return float64(n)
+}
+
+// TestGreedyWrapTokensPerLineWidths 验证各行按各自的宽度折行，长词按所在行的宽度逐段拆分。
+func TestGreedyWrapTokensPerLineWidths(t *testing.T) {
+	lines := greedyWrapTokens("aa bb cc dd", []float64{3, 100}, &runeMeasurer{}, "")
+	if len(lines) != 2 || lines[0].Content != "aa " || lines[1].Content != "bb cc dd" {
+		t.Fatalf("unexpected lines: %+v", lines)
+	}
+
+	lines = greedyWrapTokens(strings.Repeat("x", 25), []float64{5, 10}, &runeMeasurer{}, "")
+	want := []string{"xxxxx", "xxxxxxxxxx", "xxxxxxxxxx"}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %+v", len(want), lines)
+	}
+	for i, ln := range lines {
+		if ln.Content != want[i] {
+			t.Fatalf("line %d: got %q, want %q", i, ln.Content, want[i])
+		}
+	}
+}
+
+// TestFirstChunkByWidth 验证只返回放得下的第一段（至少一个字符），且测量不超过该段之后的一个字符。
+func TestFirstChunkByWidth(t *testing.T) {
+	m := &runeMeasurer{}
+	if got := firstChunkByWidth(strings.Repeat("a", 1000), 4, m); got != "aaaa" {
+		t.Fatalf("got %q, want %q", got, "aaaa")
+	}
+	if m.longest > 5 {
+		t.Fatalf("measured %d runes, expected to stop after the limit", m.longest)
+	}
+	if got := firstChunkByWidth("中文", 0.5, &runeMeasurer{}); got != "中" {
+		t.Fatalf("expected at least one rune, got %q", got)
+	}
+	if got := firstChunkByWidth("abc", 10, &runeMeasurer{}); got != "abc" {
+		t.Fatalf("expected the whole token, got %q", got)
+	}
+}
@@ -0,0 +1,545 @@
+package schema
+
+import (
+	"github.com/ByLCY/papyrus/binding"
+	"github.com/ByLCY/papyrus/dsl"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// 该文件从 DSL 文档推断数据结构：遍历所有 `${...}` 插值与控制语句中的表达式，
+// 把引用的路径合并为一棵节点树，再转换为 JSON Schema。
+//
+// 推断规则：
+//   - 成员访问（a.b）使 a 成为对象，数字下标与 for 的遍历对象成为数组，循环变量指向数组元素；
+//   - 算术运算、与数字字面量比较以及 formatMoney/formatFloat 与聚合函数 sum/avg/min/max 的参数推断为数字，
+//     与字符串/布尔字面量比较推断为对应类型；
+//   - 只用于判空或真值判断的路径（if 条件、`x != nil`、default(x, ...)、`x || y` 左侧）不是必需字段，
+//     在 `if x` / `if x != nil` 的块内，x 本身也不计为必需字段。
+
+// Infer 推断 doc 所需的数据结构。表达式存在语法错误时返回带位置的错误。
+func Infer(doc *dsl.Document) (*Schema, error) {
+	in := &inferrer{root: &node{kind: "object"}}
+	if doc != nil {
+		for _, section := range doc.Sections {
+			var block *dsl.Block
+			switch {
+			case section.Meta != nil:
+				block = section.Meta.Block
+			case section.PageSet != nil:
+				block = section.PageSet.Block
+			case section.Page != nil:
+				block = section.Page.Block
+			}
+			if err := in.walk(block, nil, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+	s := in.root.schema()
+	s.SchemaURI = Draft
+	return s, nil
+}
+
+// node 是推断过程中的数据节点。
+type node struct {
+	kind     string // object/array/number/string/boolean；空表示未知
+	props    map[string]*node
+	required map[string]bool
+	items    *node
+}
+
+// hint 在类型未知时设置类型，已有类型时保持不变。
+func (n *node) hint(kind string) {
+	if n != nil && n.kind == "" {
+		n.kind = kind
+	}
+}
+
+// prop 返回对象属性节点，不存在时创建。
+func (n *node) prop(name string) *node {
+	n.hint("object")
+	if n.props == nil {
+		n.props = map[string]*node{}
+	}
+	child, ok := n.props[name]
+	if !ok {
+		child = &node{}
+		n.props[name] = child
+	}
+	return child
+}
+
+// elem 返回数组元素节点，不存在时创建。
+func (n *node) elem() *node {
+	n.hint("array")
+	if n.items == nil {
+		n.items = &node{}
+	}
+	return n.items
+}
+
+func (n *node) schema() *Schema {
+	s := &Schema{Type: n.kind}
+	if len(n.props) > 0 {
+		s.Properties = map[string]*Schema{}
+		for _, name := range sortedKeys(n.props) {
+			s.Properties[name] = n.props[name].schema()
+			if n.required[name] {
+				s.Required = append(s.Required, name)
+			}
+		}
+	}
+	if n.items != nil {
+		s.Items = n.items.schema()
+	}
+	return s
+}
+
+// edge 是路径经过的一条对象属性边。
+type edge struct {
+	parent *node
+	name   string
+}
+
+func (e edge) child() *node { return e.parent.props[e.name] }
+
+// env 记录 let/for 引入的变量；opaque 变量（loop 与非路径的 let）不对应数据节点。
+type env struct {
+	parent *env
+	name   string
+	node   *node
+	opaque bool
+}
+
+func (e *env) lookup(name string) (*env, bool) {
+	for cur := e; cur != nil; cur = cur.parent {
+		if cur.name == name {
+			return cur, true
+		}
+	}
+	return nil, false
+}
+
+func (e *env) with(name string, n *node, opaque bool) *env {
+	return &env{parent: e, name: name, node: n, opaque: opaque}
+}
+
+type inferrer struct {
+	root *node
+}
+
+// walk 遍历 block 中的语句；guards 为当前块中已判空的节点。
+func (in *inferrer) walk(block *dsl.Block, vars *env, guards map[*node]bool) error {
+	if block == nil {
+		return nil
+	}
+	for _, stmt := range block.Statements {
+		switch {
+		case stmt.Text != nil:
+			pos := stmt.Text.Pos
+			pos.Column++
+			pos.Offset++
+			if err := in.text(string(stmt.Text.Value), pos, vars, guards); err != nil {
+				return err
+			}
+		case stmt.Assignment != nil:
+			if err := in.value(stmt.Assignment.Value, vars, guards); err != nil {
+				return err
+			}
+			// 表格列的 field 可直接写表达式（field: item.qty）
+			if val := stmt.Assignment.Value; stmt.Assignment.Key == "field" && val != nil && val.Expr != nil {
+				expr, err := val.Expr.AST()
+				if err != nil {
+					return err
+				}
+				in.use(expr, vars, false, guards)
+			}
+		case stmt.Command != nil:
+			next, err := in.command(stmt.Command, vars, guards)
+			if err != nil {
+				return err
+			}
+			vars = next
+		}
+	}
+	return nil
+}
+
+// command 处理一条命令，返回其后语句可见的变量环境（let 会引入新变量）。
+func (in *inferrer) command(cmd *dsl.Command, vars *env, guards map[*node]bool) (*env, error) {
+	switch cmd.Name {
+	case "let":
+		if len(cmd.Args) < 3 || cmd.Args[0].Type != "Ident" {
+			return vars, nil
+		}
+		expr, err := cmd.Expr(2)
+		if err != nil {
+			return nil, err
+		}
+		if n, _, ok := in.resolve(expr, vars); ok {
+			return vars.with(cmd.Args[0].Value, n, false), nil
+		}
+		in.use(expr, vars, false, guards)
+		return vars.with(cmd.Args[0].Value, nil, true), nil
+	case "if", "elif":
+		expr, err := cmd.Expr(0)
+		if err != nil {
+			return nil, err
+		}
+		in.use(expr, vars, isPath(expr), guards)
+		inner := guards
+		if found := in.guards(expr, vars); len(found) > 0 {
+			inner = map[*node]bool{}
+			for n := range guards {
+				inner[n] = true
+			}
+			for _, n := range found {
+				inner[n] = true
+			}
+		}
+		return vars, in.walk(cmd.Block, vars, inner)
+	case "for":
+		if len(cmd.Args) < 3 || cmd.Args[0].Type != "Ident" {
+			return vars, in.walk(cmd.Block, vars, guards)
+		}
+		expr, err := cmd.Expr(2)
+		if err != nil {
+			return nil, err
+		}
+		var item *node
+		if n := in.use(expr, vars, false, guards); n != nil {
+			item = n.elem()
+		}
+		inner := vars.with("loop", nil, true).with(cmd.Args[0].Value, item, item == nil)
+		return vars, in.walk(cmd.Block, inner, guards)
+	case "table":
+		return vars, in.table(cmd, vars, guards)
+	default:
+		return vars, in.walk(cmd.Block, vars, guards)
+	}
+}
+
+// table 处理表格。数据驱动表格 `table [x in] source { columns { ... } }` 的数据源为数组，
+// columns 在行变量（默认 item）与 loop 可见的作用域中分析；footer 的聚合函数针对每个数据行求值，
+// 因此在数据源的行变量以及字面量 row 所在的作用域（如 for 的循环变量）中分析。其余语句按普通块处理。
+func (in *inferrer) table(cmd *dsl.Command, vars *env, guards map[*node]bool) error {
+	if cmd.Block == nil {
+		return nil
+	}
+	var columns, footers []*dsl.Command
+	rest := &dsl.Block{}
+	for _, stmt := range cmd.Block.Statements {
+		if stmt.Command != nil && stmt.Command.Name == "columns" {
+			columns = append(columns, stmt.Command)
+			continue
+		}
+		if stmt.Command != nil && stmt.Command.Name == "footer" {
+			footers = append(footers, stmt.Command)
+			continue
+		}
+		rest.Statements = append(rest.Statements, stmt)
+	}
+	name, source, _ := dsl.SourceArgs(cmd.Args)
+	if name == "" {
+		name = "item"
+	}
+	rowVars := vars
+	if source != nil && len(columns) > 0 {
+		expr, err := dsl.ParseLexemes(source)
+		if err != nil {
+			return err
+		}
+		var item *node
+		if src := in.use(expr, vars, false, guards); src != nil {
+			item = src.elem()
+		}
+		rowVars = vars.with("loop", nil, true).with(name, item, item == nil)
+	}
+	for _, c := range columns {
+		if err := in.walk(c.Block, rowVars, guards); err != nil {
+			return err
+		}
+	}
+	if len(footers) > 0 {
+		scopes, _, err := in.rowScopes(rest, vars)
+		if err != nil {
+			return err
+		}
+		if len(columns) > 0 || len(scopes) == 0 {
+			scopes = append(scopes, rowVars)
+		}
+		for _, scope := range scopes {
+			for _, c := range footers {
+				if err := in.walk(c.Block, scope, guards); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return in.walk(rest, vars, guards)
+}
+
+// rowScopes 返回 block 内（含 for 与 if/elif/else 的嵌套块）字面量 row 所在的作用域，
+// direct 表示 block 自身直接包含 row。路径只解析不记录必需字段，行本身的分析由 walk 完成。
+func (in *inferrer) rowScopes(block *dsl.Block, vars *env) (scopes []*env, direct bool, err error) {
+	if block == nil {
+		return nil, false, nil
+	}
+	for _, stmt := range block.Statements {
+		cmd := stmt.Command
+		if cmd == nil {
+			continue
+		}
+		switch cmd.Name {
+		case "row":
+			direct = true
+		case "let":
+			if len(cmd.Args) < 3 || cmd.Args[0].Type != "Ident" {
+				continue
+			}
+			expr, err := cmd.Expr(2)
+			if err != nil {
+				return nil, false, err
+			}
+			n, _, ok := in.resolve(expr, vars)
+			vars = vars.with(cmd.Args[0].Value, n, !ok)
+		case "if", "elif", "else":
+			nested, inner, err := in.rowScopes(cmd.Block, vars)
+			if err != nil {
+				return nil, false, err
+			}
+			scopes = append(scopes, nested...)
+			direct = direct || inner
+		case "for":
+			if len(cmd.Args) < 3 || cmd.Args[0].Type != "Ident" {
+				continue
+			}
+			expr, err := cmd.Expr(2)
+			if err != nil {
+				return nil, false, err
+			}
+			var item *node
+			if n, _, ok := in.resolve(expr, vars); ok {
+				item = n.elem()
+			}
+			loopVars := vars.with("loop", nil, true).with(cmd.Args[0].Value, item, item == nil)
+			nested, inner, err := in.rowScopes(cmd.Block, loopVars)
+			if err != nil {
+				return nil, false, err
+			}
+			if inner {
+				scopes = append(scopes, loopVars)
+			}
+			scopes = append(scopes, nested...)
+		}
+	}
+	return scopes, direct, nil
+}
+
+// text 分析字符串中的 `${...}` 占位符。
+func (in *inferrer) text(text string, pos lexer.Position, vars *env, guards map[*node]bool) error {
+	exprs, err := binding.Placeholders(text, pos)
+	if err != nil {
+		return err
+	}
+	for _, expr := range exprs {
+		in.use(expr, vars, false, guards)
+	}
+	return nil
+}
+
+// value 分析赋值中的字符串（含数组与内联对象中的字符串）。
+func (in *inferrer) value(val *dsl.Value, vars *env, guards map[*node]bool) error {
+	if val == nil {
+		return nil
+	}
+	if val.String != nil {
+		pos := val.Pos
+		pos.Column++
+		pos.Offset++
+		return in.text(string(*val.String), pos, vars, guards)
+	}
+	if val.Array != nil {
+		for _, item := range val.Array.Values {
+			if err := in.value(item, vars, guards); err != nil {
+				return err
+			}
+		}
+	}
+	if val.Object != nil {
+		for _, entry := range val.Object.Entries {
+			if err := in.value(entry.Value, vars, guards); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve 将路径表达式映射到数据节点，并返回沿途经过的对象属性边。
+func (in *inferrer) resolve(expr dsl.Expr, vars *env) (*node, []edge, bool) {
+	switch e := expr.(type) {
+	case *dsl.IdentExpr:
+		if v, ok := vars.lookup(e.Name); ok {
+			return v.node, nil, !v.opaque && v.node != nil
+		}
+		if e.Name == binding.RootName {
+			return in.root, nil, true
+		}
+		return in.root.prop(e.Name), []edge{{in.root, e.Name}}, true
+	case *dsl.MemberExpr:
+		target, edges, ok := in.resolve(e.Target, vars)
+		if !ok {
+			return nil, nil, false
+		}
+		return target.prop(e.Name), append(edges, edge{target, e.Name}), true
+	case *dsl.IndexExpr:
+		target, edges, ok := in.resolve(e.Target, vars)
+		if !ok {
+			return nil, nil, false
+		}
+		if lit, isLit := e.Index.(*dsl.LiteralExpr); isLit {
+			if key, isKey := lit.Value.(string); isKey {
+				return target.prop(key), append(edges, edge{target, key}), true
+			}
+		}
+		return target.elem(), edges, true
+	}
+	return nil, nil, false
+}
+
+// use 记录表达式对数据的使用。optional 表示表达式只用于判空/真值判断；返回路径表达式对应的节点。
+func (in *inferrer) use(expr dsl.Expr, vars *env, optional bool, guards map[*node]bool) *node {
+	switch e := expr.(type) {
+	case *dsl.IdentExpr, *dsl.MemberExpr:
+		return in.usePath(expr, vars, optional, guards)
+	case *dsl.IndexExpr:
+		if _, isLit := e.Index.(*dsl.LiteralExpr); !isLit {
+			in.use(e.Index, vars, false, guards)
+		}
+		return in.usePath(expr, vars, optional, guards)
+	case *dsl.UnaryExpr:
+		if e.Op == "!" {
+			in.use(e.Operand, vars, true, guards)
+			return nil
+		}
+		in.use(e.Operand, vars, optional, guards).hint("number")
+	case *dsl.BinaryExpr:
+		switch e.Op {
+		case "&&", "||":
+			in.use(e.Left, vars, true, guards)
+			in.use(e.Right, vars, optional, guards)
+		case "==", "!=":
+			if isNil(e.Left) || isNil(e.Right) {
+				in.use(e.Left, vars, true, guards)
+				in.use(e.Right, vars, true, guards)
+				return nil
+			}
+			in.use(e.Left, vars, optional, guards).hint(literalKind(e.Right))
+			in.use(e.Right, vars, optional, guards).hint(literalKind(e.Left))
+		case "<", "<=", ">", ">=":
+			in.use(e.Left, vars, optional, guards).hint(literalKind(e.Right))
+			in.use(e.Right, vars, optional, guards).hint(literalKind(e.Left))
+		case "+":
+			in.use(e.Left, vars, optional, guards)
+			in.use(e.Right, vars, optional, guards)
+		default:
+			in.use(e.Left, vars, optional, guards).hint("number")
+			in.use(e.Right, vars, optional, guards).hint("number")
+		}
+	case *dsl.CondExpr:
+		in.use(e.Cond, vars, true, guards)
+		in.use(e.Then, vars, optional, guards)
+		in.use(e.Else, vars, optional, guards)
+	case *dsl.CallExpr:
+		for i, arg := range e.Args {
+			argOptional := optional || (e.Func == "default" && i == 0)
+			n := in.use(arg, vars, argOptional, guards)
+			if i == 0 {
+				switch e.Func {
+				case "formatMoney", "formatFloat", "sum", "avg", "min", "max":
+					n.hint("number")
+				case "join":
+					n.hint("array")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (in *inferrer) usePath(expr dsl.Expr, vars *env, optional bool, guards map[*node]bool) *node {
+	n, edges, ok := in.resolve(expr, vars)
+	if !ok {
+		return nil
+	}
+	if !optional {
+		for _, e := range edges {
+			if guards[e.child()] {
+				continue
+			}
+			if e.parent.required == nil {
+				e.parent.required = map[string]bool{}
+			}
+			e.parent.required[e.name] = true
+		}
+	}
+	return n
+}
+
+// guards 返回条件为真时必然存在的节点：`x`、`x != nil`、`!(x == nil)` 以及 && 的两侧。
+func (in *inferrer) guards(expr dsl.Expr, vars *env) []*node {
+	switch e := expr.(type) {
+	case *dsl.IdentExpr, *dsl.MemberExpr, *dsl.IndexExpr:
+		if n, _, ok := in.resolve(expr, vars); ok {
+			return []*node{n}
+		}
+	case *dsl.BinaryExpr:
+		switch e.Op {
+		case "&&":
+			return append(in.guards(e.Left, vars), in.guards(e.Right, vars)...)
+		case "!=":
+			if isNil(e.Right) {
+				return in.guards(e.Left, vars)
+			}
+			if isNil(e.Left) {
+				return in.guards(e.Right, vars)
+			}
+		}
+	case *dsl.UnaryExpr:
+		if inner, ok := e.Operand.(*dsl.BinaryExpr); ok && e.Op == "!" && inner.Op == "==" {
+			return in.guards(&dsl.BinaryExpr{Pos: inner.Pos, Op: "!=", Left: inner.Left, Right: inner.Right}, vars)
+		}
+	}
+	return nil
+}
+
+func isPath(expr dsl.Expr) bool {
+	switch expr.(type) {
+	case *dsl.IdentExpr, *dsl.MemberExpr, *dsl.IndexExpr:
+		return true
+	}
+	return false
+}
+
+func isNil(expr dsl.Expr) bool {
+	lit, ok := expr.(*dsl.LiteralExpr)
+	return ok && lit.Value == nil
+}
+
+// literalKind 返回字面量对应的 JSON 类型，非字面量返回空字符串。
+func literalKind(expr dsl.Expr) string {
+	lit, ok := expr.(*dsl.LiteralExpr)
+	if !ok {
+		return ""
+	}
+	switch lit.Value.(type) {
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	return ""
+}
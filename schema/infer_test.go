@@ -0,0 +1,141 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ByLCY/papyrus/dsl"
+)
+
+func inferText(t *testing.T, dslText string) *Schema {
+	t.Helper()
+	doc, err := dsl.Parse(strings.NewReader(dslText))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	s, err := Infer(doc)
+	if err != nil {
+		t.Fatalf("推断失败: %v", err)
+	}
+	return s
+}
+
+// TestInfer 验证插值、循环与条件中的路径被合并为对象/数组结构，并推断类型与必需字段。
+func TestInfer(t *testing.T) {
+	s := inferText(t, `doc T v1 {
+  meta { title: "${data.title}" }
+  page A4 portrait margin 10mm {
+    flow {
+      let customer = data.order.customer
+      text { "客户：${customer.name} ${default(customer.phone, '-')}" }
+      for item in data.order.items {
+        text { "${loop.index}. ${item.name} × ${item.qty} = ${formatMoney(item.price * item.qty, '¥')}" }
+      }
+      if data.summary {
+        text { "${data.summary.total}" }
+      }
+      if data.status == "paid" { text { "已支付" } }
+      text { "${join(data.tags)}" }
+      table columns 2 { row { cell { "${data.note}" } } }
+      table line in data.order.items {
+        columns { column { header: "SKU"; field: line.sku } }
+        footer { cell { "${sum(line.weight)} kg / ${count()}" } }
+      }
+    }
+  }
+}`)
+	if s.SchemaURI != Draft || s.Type != "object" {
+		t.Fatalf("根节点应为对象并声明 $schema，实际 %+v", s)
+	}
+	if want := []string{"note", "order", "status", "tags", "title"}; !reflect.DeepEqual(s.Required, want) {
+		t.Fatalf("根必需字段错误: %v", s.Required)
+	}
+	order := s.Properties["order"]
+	customer := order.Properties["customer"]
+	if customer.Type != "object" || !reflect.DeepEqual(customer.Required, []string{"name"}) {
+		t.Fatalf("customer 推断错误: %+v", customer)
+	}
+	if _, ok := customer.Properties["phone"]; !ok {
+		t.Fatalf("default() 引用的可选字段应出现在 properties 中")
+	}
+	items := order.Properties["items"]
+	if items.Type != "array" || items.Items == nil || items.Items.Type != "object" {
+		t.Fatalf("for 遍历的路径应推断为对象数组: %+v", items)
+	}
+	if want := []string{"name", "price", "qty", "sku", "weight"}; !reflect.DeepEqual(items.Items.Required, want) {
+		t.Fatalf("数组元素必需字段错误: %v", items.Items.Required)
+	}
+	if items.Items.Properties["price"].Type != "number" || items.Items.Properties["qty"].Type != "number" {
+		t.Fatalf("算术运算的操作数应推断为数字")
+	}
+	if items.Items.Properties["weight"].Type != "number" {
+		t.Fatalf("footer 中聚合函数的参数应在行变量作用域中推断为数字")
+	}
+	if _, ok := order.Properties["loop"]; ok {
+		t.Fatalf("loop 不应被当作数据字段")
+	}
+	summary := s.Properties["summary"]
+	if summary.Type != "object" || !reflect.DeepEqual(summary.Required, []string{"total"}) {
+		t.Fatalf("if 守卫的对象推断错误: %+v", summary)
+	}
+	if s.Properties["status"].Type != "string" || s.Properties["tags"].Type != "array" {
+		t.Fatalf("比较与 join 的类型推断错误")
+	}
+
+	example, err := json.Marshal(s.Example())
+	if err != nil {
+		t.Fatalf("序列化示例失败: %v", err)
+	}
+	want := `{"note":"","order":{"customer":{"name":"","phone":""},"items":[{"name":"","price":0,"qty":0,"sku":"","weight":0}]},"status":"","summary":{"total":""},"tags":[],"title":""}`
+	if string(example) != want {
+		t.Fatalf("示例数据错误:\n%s\n期望:\n%s", example, want)
+	}
+}
+
+// TestInferFooterLiteralRows 验证 footer 的聚合函数在字面量 row 所在 for 循环的作用域中推断，
+// 循环变量不会被当作根对象的字段。
+func TestInferFooterLiteralRows(t *testing.T) {
+	s := inferText(t, `doc T v1 {
+  page A4 portrait margin 10mm {
+    flow {
+      table {
+        header { cell { "名称" } cell { "数量" } }
+        for item in data.items {
+          row { cell { "${item.name}" } cell { "${item.qty}" } }
+        }
+        footer { cell { "合计" } cell { "${sum(item.qty)} / ${count()} / ${avg(item.qty)}" } }
+      }
+    }
+  }
+}`)
+	if _, ok := s.Properties["item"]; ok {
+		t.Fatalf("循环变量 item 不应出现在根对象中: %v", s.Required)
+	}
+	if want := []string{"items"}; !reflect.DeepEqual(s.Required, want) {
+		t.Fatalf("根必需字段错误: %v", s.Required)
+	}
+	elem := s.Properties["items"].Items
+	if want := []string{"name", "qty"}; elem == nil || !reflect.DeepEqual(elem.Required, want) {
+		t.Fatalf("数组元素推断错误: %+v", elem)
+	}
+	if elem.Properties["qty"].Type != "number" {
+		t.Fatalf("footer 中聚合函数的参数应推断为数字")
+	}
+}
+
+// TestInferSyntaxError 验证表达式语法错误带有源码位置。
+func TestInferSyntaxError(t *testing.T) {
+	doc, err := dsl.Parse(strings.NewReader(`doc T v1 {
+  page A4 portrait margin 10mm {
+    flow { text { "${a +}" } }
+  }
+}`))
+	if err != nil {
+		t.Fatalf("解析 DSL 失败: %v", err)
+	}
+	if _, err := Infer(doc); err == nil || !strings.HasPrefix(err.Error(), "3:") {
+		t.Fatalf("期望第 3 行的语法错误，实际 %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+// Package schema 描述模板所需的数据结构：从 dsl.Document 推断 JSON Schema（draft 2020-12 子集），
+// 并可生成示例数据骨架。
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Draft 是生成的 Schema 声明的 JSON Schema 版本。
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema 是 JSON Schema 的子集：type、properties、required、items、enum 与 format，可用于推断结果的输出与数据校验。
+// type 可以是单个类型，也可以是类型数组（如 ["string", "null"]），后者解析到 Types。
+type Schema struct {
+	SchemaURI   string             `json:"$schema,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Types       []string           `json:"-"` // 类型数组形式的 type，非空时 Type 为空
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []any              `json:"enum,omitempty"`
+	Format      string             `json:"format,omitempty"`
+}
+
+// schemaFields 与 Schema 字段相同但没有方法，供自定义 JSON 编解码复用默认规则。
+type schemaFields Schema
+
+// UnmarshalJSON 解析 Schema，type 可以是字符串或字符串数组；只含一个元素的数组按单个类型处理。
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		*schemaFields
+		Type json.RawMessage `json:"type,omitempty"`
+	}{schemaFields: (*schemaFields)(s)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	s.Type, s.Types = "", nil
+	if len(aux.Type) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(aux.Type, &s.Type); err == nil {
+		return nil
+	}
+	var types []string
+	if err := json.Unmarshal(aux.Type, &types); err != nil {
+		return fmt.Errorf("type 应为字符串或字符串数组: %s", aux.Type)
+	}
+	if len(types) == 1 {
+		s.Type = types[0]
+	} else {
+		s.Types = types
+	}
+	return nil
+}
+
+// MarshalJSON 输出 Schema，Types 非空时 type 输出为数组；字段顺序与结构体声明一致。
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	var typ any
+	if len(s.Types) > 0 {
+		typ = s.Types
+	} else if s.Type != "" {
+		typ = s.Type
+	}
+	return json.Marshal(struct {
+		SchemaURI   string             `json:"$schema,omitempty"`
+		Type        any                `json:"type,omitempty"`
+		Description string             `json:"description,omitempty"`
+		Properties  map[string]*Schema `json:"properties,omitempty"`
+		Required    []string           `json:"required,omitempty"`
+		Items       *Schema            `json:"items,omitempty"`
+		Enum        []any              `json:"enum,omitempty"`
+		Format      string             `json:"format,omitempty"`
+	}{s.SchemaURI, typ, s.Description, s.Properties, s.Required, s.Items, s.Enum, s.Format})
+}
+
+// AllowedTypes 返回 Schema 允许的类型，未声明时为空。
+func (s *Schema) AllowedTypes() []string {
+	if len(s.Types) > 0 {
+		return s.Types
+	}
+	if s.Type != "" {
+		return []string{s.Type}
+	}
+	return nil
+}
+
+// Example 生成符合 Schema 结构的示例数据：对象递归展开属性，数组包含一个示例元素，
+// 字符串为空串、数字为 0、布尔为 false；未声明类型的叶子按字符串处理。
+func (s *Schema) Example() any {
+	if s == nil {
+		return nil
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+	// 类型数组取第一个非 null 的类型
+	typ := s.Type
+	for _, t := range s.Types {
+		if typ = t; t != "null" {
+			break
+		}
+	}
+	switch typ {
+	case "object":
+		out := map[string]any{}
+		for name, prop := range s.Properties {
+			out[name] = prop.Example()
+		}
+		return out
+	case "array":
+		if s.Items == nil {
+			return []any{}
+		}
+		return []any{s.Items.Example()}
+	case "number", "integer":
+		return 0
+	case "boolean":
+		return false
+	case "null":
+		return nil
+	default:
+		return ""
+	}
+}
+
+// MarshalIndent 以缩进格式输出 Schema JSON。
+func (s *Schema) MarshalIndent() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// sortedKeys 返回 map 的有序键，用于稳定输出。
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,238 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// 该文件按 Schema 校验绑定数据，支持 type、required、properties、items、enum 与 format，
+// 其余关键字忽略。错误以 JSON Pointer（RFC 6901）标识数据位置。
+
+// Parse 解析 JSON Schema 文本。
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("解析 JSON Schema 失败: %w", err)
+	}
+	return &s, nil
+}
+
+// Violation 描述一处不符合 Schema 的数据。
+type Violation struct {
+	Pointer string `json:"pointer"` // 数据位置的 JSON Pointer，根为空字符串
+	Message string `json:"message"`
+}
+
+// String 以 `"/items/0/qty": 原因` 的形式描述问题。
+func (v Violation) String() string {
+	return fmt.Sprintf("%q: %s", v.Pointer, v.Message)
+}
+
+// ValidationError 汇总数据校验发现的所有问题。
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.String()
+	}
+	return fmt.Sprintf("数据不符合 Schema（%d 处）: %s", len(e.Violations), strings.Join(parts, "; "))
+}
+
+// Validate 校验 data 是否符合 Schema，不符合时返回 *ValidationError。
+// data 可以是 JSON 解码得到的值，也可以是结构体等 Go 值（按 encoding/json 的规则转换后校验）。
+func (s *Schema) Validate(data any) error {
+	if s == nil {
+		return nil
+	}
+	val, err := jsonValue(data)
+	if err != nil {
+		return fmt.Errorf("无法将数据转换为 JSON: %w", err)
+	}
+	var v validator
+	v.check(s, val, "")
+	if len(v.violations) > 0 {
+		return &ValidationError{Violations: v.violations}
+	}
+	return nil
+}
+
+type validator struct {
+	violations []Violation
+}
+
+func (v *validator) fail(pointer, format string, args ...any) {
+	v.violations = append(v.violations, Violation{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) check(s *Schema, val any, pointer string) {
+	if s == nil {
+		return
+	}
+	if types := s.AllowedTypes(); len(types) > 0 && !hasAnyType(val, types) {
+		v.fail(pointer, "应为 %s，实际为 %s", strings.Join(types, " 或 "), jsonType(val))
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(val, s.Enum) {
+		v.fail(pointer, "取值应为 %s 之一", formatEnum(s.Enum))
+	}
+	if s.Format != "" {
+		if str, ok := val.(string); ok && !checkFormat(s.Format, str) {
+			v.fail(pointer, "不符合 %s 格式: %q", s.Format, str)
+		}
+	}
+	switch typed := val.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := typed[name]; !ok {
+				v.fail(pointer+"/"+escapePointer(name), "缺少必需字段")
+			}
+		}
+		for _, name := range sortedKeys(s.Properties) {
+			if child, ok := typed[name]; ok {
+				v.check(s.Properties[name], child, pointer+"/"+escapePointer(name))
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range typed {
+				v.check(s.Items, item, fmt.Sprintf("%s/%d", pointer, i))
+			}
+		}
+	}
+}
+
+// jsonValue 将 data 转换为 encoding/json 解码后的通用形式（map[string]any、[]any、float64 等）。
+// 已是该形式（逐层检查）的数据直接返回，其余数据（如 int、[]string、结构体）经 encoding/json 往返转换。
+func jsonValue(data any) (any, error) {
+	if isJSONValue(data) {
+		return data, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// isJSONValue 判断 data 及其包含的所有值是否都已是 encoding/json 解码后的通用形式。
+func isJSONValue(data any) bool {
+	switch typed := data.(type) {
+	case nil, string, float64, bool:
+		return true
+	case map[string]any:
+		for _, v := range typed {
+			if !isJSONValue(v) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		for _, v := range typed {
+			if !isJSONValue(v) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func hasAnyType(val any, types []string) bool {
+	for _, typ := range types {
+		if hasType(val, typ) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasType(val any, typ string) bool {
+	switch typ {
+	case "integer":
+		f, ok := val.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := val.(float64)
+		return ok
+	}
+	return jsonType(val) == typ
+}
+
+// jsonType 返回值的 JSON 类型名称。
+func jsonType(val any) string {
+	switch val.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", val)
+}
+
+func inEnum(val any, enum []any) bool {
+	for _, candidate := range enum {
+		if c, err := jsonValue(candidate); err == nil && reflect.DeepEqual(val, c) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(enum []any) string {
+	raw, err := json.Marshal(enum)
+	if err != nil {
+		return fmt.Sprint(enum)
+	}
+	return string(raw)
+}
+
+// checkFormat 校验常见的 format；未知的 format 仅作标注，不参与校验。
+func checkFormat(format, s string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05Z07:00", s)
+		if err != nil {
+			_, err = time.Parse("15:04:05", s)
+		}
+		return err == nil
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	}
+	return true
+}
+
+// escapePointer 按 RFC 6901 转义 JSON Pointer 中的属性名。
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
@@ -0,0 +1,159 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const orderSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "required": ["no", "items", "status"],
+  "properties": {
+    "no": {"type": "string"},
+    "date": {"type": "string", "format": "date"},
+    "status": {"enum": ["paid", "open"]},
+    "items": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["name", "qty"],
+        "properties": {"name": {"type": "string"}, "qty": {"type": "integer"}}
+      }
+    },
+    "a/b": {"type": "number"}
+  }
+}`
+
+// TestValidate 验证类型、必需字段、枚举与格式错误，并以 JSON Pointer 标识位置。
+func TestValidate(t *testing.T) {
+	s, err := Parse([]byte(orderSchema))
+	if err != nil {
+		t.Fatalf("解析 Schema 失败: %v", err)
+	}
+	valid := map[string]any{
+		"no":     "INV-1",
+		"date":   "2024-05-01",
+		"status": "paid",
+		"items":  []any{map[string]any{"name": "A", "qty": 2.0}},
+	}
+	if err := s.Validate(valid); err != nil {
+		t.Fatalf("合法数据不应报错: %v", err)
+	}
+
+	invalid := map[string]any{
+		"no":     42.0,
+		"date":   "05/01/2024",
+		"status": "void",
+		"items":  []any{map[string]any{"name": "A", "qty": 1.5}, map[string]any{"qty": 1.0}},
+		"a/b":    "x",
+	}
+	err = s.Validate(invalid)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("期望 *ValidationError，实际 %v", err)
+	}
+	var pointers []string
+	for _, v := range verr.Violations {
+		pointers = append(pointers, v.Pointer)
+	}
+	want := []string{"/a~1b", "/date", "/items/0/qty", "/items/1/name", "/no", "/status"}
+	if !reflect.DeepEqual(pointers, want) {
+		t.Fatalf("错误位置不符: %v\n%v", pointers, err)
+	}
+
+	if err := s.Validate([]any{}); err == nil || err.Error() != `数据不符合 Schema（1 处）: "": 应为 object，实际为 array` {
+		t.Fatalf("根类型错误信息不符: %v", err)
+	}
+}
+
+// TestValidateStruct 验证结构体数据按 json 标签转换后校验。
+func TestValidateStruct(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+		Qty  int    `json:"qty"`
+	}
+	type order struct {
+		No     string `json:"no"`
+		Status string `json:"status"`
+		Items  []item `json:"items"`
+	}
+	s, err := Parse([]byte(orderSchema))
+	if err != nil {
+		t.Fatalf("解析 Schema 失败: %v", err)
+	}
+	if err := s.Validate(order{No: "1", Status: "open", Items: []item{{Name: "A", Qty: 1}}}); err != nil {
+		t.Fatalf("结构体数据不应报错: %v", err)
+	}
+	if err := s.Validate(&order{No: "1", Status: "open"}); err == nil {
+		t.Fatalf("nil 切片应序列化为 null 并报类型错误")
+	}
+}
+
+// TestValidateGoValues 验证 map[string]any 中嵌套的 int、[]string 等 Go 值先按 JSON 规则转换再校验。
+func TestValidateGoValues(t *testing.T) {
+	s, err := Parse([]byte(`{
+  "type": "object",
+  "properties": {
+    "qty": {"type": "integer"},
+    "tags": {"type": "array", "items": {"type": "string"}},
+    "items": {"type": "array", "items": {"type": "object", "properties": {"qty": {"type": "integer"}}}}
+  }
+}`))
+	if err != nil {
+		t.Fatalf("解析 Schema 失败: %v", err)
+	}
+	data := map[string]any{
+		"qty":   3,
+		"tags":  []string{"a"},
+		"items": []any{map[string]any{"qty": int64(2)}},
+	}
+	if err := s.Validate(data); err != nil {
+		t.Fatalf("嵌套的 Go 值不应报错: %v", err)
+	}
+	bad := map[string]any{"tags": []int{1}}
+	if err := s.Validate(bad); err == nil || err.Error() != `数据不符合 Schema（1 处）: "/tags/0": 应为 string，实际为 number` {
+		t.Fatalf("嵌套 Go 值的错误信息不符: %v", err)
+	}
+}
+
+// TestTypeArray 验证 type 可写成数组（如可为空的字段），并在输出时保持数组形式。
+func TestTypeArray(t *testing.T) {
+	s, err := Parse([]byte(`{
+  "type": "object",
+  "properties": {
+    "note": {"type": ["string", "null"]},
+    "no": {"type": ["string"]}
+  }
+}`))
+	if err != nil {
+		t.Fatalf("解析 Schema 失败: %v", err)
+	}
+	note := s.Properties["note"]
+	if !reflect.DeepEqual(note.AllowedTypes(), []string{"string", "null"}) || s.Properties["no"].Type != "string" {
+		t.Fatalf("type 数组解析不符: %+v %+v", note, s.Properties["no"])
+	}
+	for _, data := range []any{map[string]any{"note": nil}, map[string]any{"note": "x"}} {
+		if err := s.Validate(data); err != nil {
+			t.Fatalf("%v 不应报错: %v", data, err)
+		}
+	}
+	if err := s.Validate(map[string]any{"note": 1.0}); err == nil || err.Error() != `数据不符合 Schema（1 处）: "/note": 应为 string 或 null，实际为 number` {
+		t.Fatalf("type 数组的错误信息不符: %v", err)
+	}
+	if note.Example() != "" {
+		t.Fatalf("示例应取第一个非 null 类型，实际 %v", note.Example())
+	}
+	raw, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("输出 Schema 失败: %v", err)
+	}
+	if want := `{"type":"object","properties":{"no":{"type":"string"},"note":{"type":["string","null"]}}}`; string(raw) != want {
+		t.Fatalf("输出不符:\n%s\n%s", raw, want)
+	}
+	if _, err := Parse([]byte(`{"type": 1}`)); err == nil {
+		t.Fatalf("type 为数字时应报错")
+	}
+}